@@ -0,0 +1,250 @@
+// Command mock-oidc is a minimal OpenID Connect provider for trying out
+// AUTH_MODE=oidc locally. It signs in whoever submits its login form, with
+// whatever email and groups they type, so never expose it.
+//
+//	go run ./cmd/mock-oidc
+//	AUTH_MODE=oidc OIDC_ISSUER_URL=http://localhost:9998 OIDC_CLIENT_ID=zep-web \
+//	  OIDC_ALLOWED_DOMAINS=example.com go run main.go
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// authorization is what a code was issued for
+type authorization struct {
+	clientID    string
+	redirectURI string
+	nonce       string
+	challenge   string
+	email       string
+	groups      []string
+	expiresAt   time.Time
+}
+
+type provider struct {
+	issuer string
+	key    *rsa.PrivateKey
+
+	mutex sync.Mutex
+	codes map[string]authorization
+}
+
+var loginForm = template.Must(template.New("login").Parse(`<!doctype html>
+<html>
+<head><title>Mock OIDC login</title></head>
+<body style="font-family: sans-serif; max-width: 24rem; margin: 4rem auto">
+<h1>Mock OIDC login</h1>
+<form method="post">
+  {{ range $name, $values := .Params }}{{ range $values }}<input type="hidden" name="{{ $name }}" value="{{ . }}">{{ end }}{{ end }}
+  <p><label>Email<br><input name="email" value="{{ .Email }}" size="32"></label></p>
+  <p><label>Groups (comma-separated)<br><input name="groups" value="{{ .Groups }}" size="32"></label></p>
+  <p><button type="submit">Sign in</button></p>
+</form>
+</body>
+</html>`))
+
+func main() {
+	addr := getEnv("MOCK_OIDC_ADDR", "localhost:9998")
+	issuer := getEnv("MOCK_OIDC_ISSUER", "http://"+addr)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("Failed to generate signing key: %v", err)
+	}
+
+	p := &provider{issuer: issuer, key: key, codes: make(map[string]authorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/jwks", p.jwks)
+
+	log.Printf("🧪 Mock OIDC provider at %s", issuer)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+func (p *provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize shows the login form, then redirects back with a code
+func (p *provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		params := url.Values{}
+		for _, name := range []string{"client_id", "redirect_uri", "state", "nonce", "code_challenge", "code_challenge_method"} {
+			params.Set(name, r.Form.Get(name))
+		}
+		loginForm.Execute(w, map[string]interface{}{
+			"Params": params,
+			"Email":  getEnv("MOCK_OIDC_EMAIL", "operator@example.com"),
+			"Groups": getEnv("MOCK_OIDC_GROUPS", "zep-admins"),
+		})
+		return
+	}
+
+	if r.Form.Get("code_challenge_method") != "S256" || r.Form.Get("code_challenge") == "" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(r.Form.Get("redirect_uri"))
+	if err != nil || redirectURI.Host == "" {
+		http.Error(w, "Invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	var groups []string
+	for _, group := range strings.Split(r.Form.Get("groups"), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+
+	code := randomString()
+	p.mutex.Lock()
+	p.codes[code] = authorization{
+		clientID:    r.Form.Get("client_id"),
+		redirectURI: r.Form.Get("redirect_uri"),
+		nonce:       r.Form.Get("nonce"),
+		challenge:   r.Form.Get("code_challenge"),
+		email:       r.Form.Get("email"),
+		groups:      groups,
+		expiresAt:   time.Now().Add(time.Minute),
+	}
+	p.mutex.Unlock()
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	query.Set("state", r.Form.Get("state"))
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// token exchanges a code for a signed ID token after checking the PKCE verifier
+func (p *provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Method != http.MethodPost {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	p.mutex.Lock()
+	auth, exists := p.codes[r.Form.Get("code")]
+	delete(p.codes, r.Form.Get("code"))
+	p.mutex.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	switch {
+	case !exists || time.Now().After(auth.expiresAt):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case auth.clientID != r.Form.Get("client_id") || auth.redirectURI != r.Form.Get("redirect_uri"):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "client or redirect_uri mismatch"})
+		return
+	case base64.RawURLEncoding.EncodeToString(verifier[:]) != auth.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "code_verifier mismatch"})
+		return
+	}
+
+	now := time.Now()
+	idToken, err := p.sign(map[string]interface{}{
+		"iss":            p.issuer,
+		"sub":            auth.email,
+		"aud":            auth.clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          auth.nonce,
+		"email":          auth.email,
+		"email_verified": true,
+		"name":           strings.Split(auth.email, "@")[0],
+		"groups":         auth.groups,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (p *provider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "mock",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *provider) sign(claims map[string]interface{}) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "mock", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,179 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes recorded for an action
+const (
+	OutcomeSuccess = "success"
+	OutcomePartial = "partial"
+	OutcomeFailure = "failure"
+)
+
+// Entry is one mutating admin action
+type Entry struct {
+	ID         string      `json:"id"`
+	Time       time.Time   `json:"time"`
+	Actor      string      `json:"actor"`
+	Role       string      `json:"role,omitempty"`
+	ClientIP   string      `json:"client_ip"`
+	Action     string      `json:"action"` // e.g. "user.update", "session.delete"
+	TargetType string      `json:"target_type"`
+	TargetID   string      `json:"target_id"`
+	Before     interface{} `json:"before,omitempty"`
+	After      interface{} `json:"after,omitempty"`
+	Outcome    string      `json:"outcome"`
+	Error      string      `json:"error,omitempty"`
+	Details    interface{} `json:"details,omitempty"`
+}
+
+// Filter selects entries; empty fields match everything
+type Filter struct {
+	Actor    string
+	Action   string
+	TargetID string
+	Outcome  string
+	Since    time.Time
+	Until    time.Time
+}
+
+// Matches reports whether the entry passes the filter. Actor and target
+// match on a case-insensitive substring, action on a prefix so "user"
+// selects every user action.
+func (f Filter) Matches(e Entry) bool {
+	if f.Actor != "" && !strings.Contains(strings.ToLower(e.Actor), strings.ToLower(f.Actor)) {
+		return false
+	}
+	if f.Action != "" && !strings.HasPrefix(e.Action, f.Action) {
+		return false
+	}
+	if f.TargetID != "" && !strings.Contains(strings.ToLower(e.TargetID), strings.ToLower(f.TargetID)) {
+		return false
+	}
+	if f.Outcome != "" && e.Outcome != f.Outcome {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Log is an append-only audit trail stored as one JSON entry per line.
+// Entries are never rewritten, so the file can be shipped or rotated with
+// ordinary log tooling.
+type Log struct {
+	path  string
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// Open opens the audit log at path for appending, creating it if needed
+func Open(path string) (*Log, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	return &Log{path: path, file: file}, nil
+}
+
+// Path returns the file the log is written to
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Record appends an entry, filling in its ID and time when unset
+func (l *Log) Record(entry Entry) error {
+	if entry.ID == "" {
+		entry.ID = newEntryID()
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, err := l.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return l.file.Sync()
+}
+
+// Query returns the entries matching the filter, newest first
+func (l *Log) Query(filter Filter) ([]Entry, error) {
+	// Take the size under the lock so a half-written line is never read,
+	// then scan up to it without holding up Record
+	l.mutex.Lock()
+	info, err := l.file.Stat()
+	l.mutex.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(io.LimitReader(file, info.Size()))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Skip damaged lines rather than hide the rest of the trail
+			continue
+		}
+		if filter.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// Close closes the underlying file
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+func newEntryID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
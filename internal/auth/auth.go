@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/schizoidcock/zep-web-interface/internal/config"
+)
+
+// SessionCookie is the name of the signed session cookie
+const SessionCookie = "zep_admin_session"
+
+// Identity is the signed-in operator
+type Identity struct {
+	Username string   `json:"sub"`
+	Email    string   `json:"email,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+	Role     Role     `json:"role"`
+}
+
+// Display is how the operator is shown in the interface
+func (i *Identity) Display() string {
+	if i.Name != "" {
+		return i.Name
+	}
+	if i.Email != "" {
+		return i.Email
+	}
+	return i.Username
+}
+
+type contextKey struct{}
+
+// FromContext returns the identity the auth middleware attached to the request
+func FromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(contextKey{}).(*Identity)
+	return identity, ok
+}
+
+// session is the payload of a signed session cookie
+type session struct {
+	Identity
+	ExpiresAt int64 `json:"exp"`
+}
+
+// Authenticator protects the admin UI and API with HTTP basic auth, a
+// shared-secret login form or OpenID Connect single sign-on. The login form
+// and SSO both keep the operator signed in with a signed session cookie.
+type Authenticator struct {
+	mode       string
+	secrets    []sharedSecret
+	secret     []byte
+	ttl        time.Duration
+	secure     bool
+	trustProxy bool
+	basePath   string
+	loginPath  string
+	oidc       *oidcProvider
+}
+
+// New creates an authenticator from the configuration. Without
+// AUTH_SESSION_SECRET a random secret is used, so sessions end on restart.
+func New(cfg *config.Config, basePath string) *Authenticator {
+	// The secret also signs CSRF tokens, so every mode needs one
+	secret := []byte(cfg.AuthSessionSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		rand.Read(secret)
+		if cfg.AuthMode == "password" || cfg.AuthMode == "oidc" {
+			log.Printf("⚠️ AUTH_SESSION_SECRET not set, sessions will not survive a restart")
+		}
+	}
+
+	switch cfg.AuthMode {
+	case "none":
+		log.Printf("⚠️⚠️⚠️ AUTH_MODE=none: authentication is DISABLED. Anyone who can reach %s can read, import and delete users and sessions as an admin", basePath)
+	default:
+		log.Printf("🔒 Authentication enabled (%s)", cfg.AuthMode)
+	}
+
+	a := &Authenticator{
+		mode: cfg.AuthMode,
+		secrets: []sharedSecret{
+			{username: cfg.AuthUsername, password: cfg.AuthPassword, role: RoleAdmin},
+			{username: string(RoleOperator), password: cfg.AuthOperatorPassword, role: RoleOperator},
+			{username: string(RoleViewer), password: cfg.AuthViewerPassword, role: RoleViewer},
+		},
+		secret:     secret,
+		ttl:        cfg.AuthSessionTTL,
+		secure:     cfg.TLSEnabled,
+		trustProxy: cfg.TrustProxy,
+		basePath:   basePath,
+		loginPath:  basePath + "/login",
+	}
+	if cfg.AuthMode == "oidc" {
+		a.oidc = newOIDCProvider(cfg)
+	}
+	return a
+}
+
+// LoginEnabled reports whether operators sign in through the login page,
+// with the shared secret or through SSO
+func (a *Authenticator) LoginEnabled() bool {
+	return a.mode == "password" || a.mode == "oidc"
+}
+
+// OIDCEnabled reports whether the login page signs in through SSO
+func (a *Authenticator) OIDCEnabled() bool {
+	return a.mode == "oidc"
+}
+
+// LoginPath is where unauthenticated browsers are sent
+func (a *Authenticator) LoginPath() string {
+	return a.loginPath
+}
+
+// Middleware rejects requests without valid credentials and attaches the
+// identity of authenticated ones to the request context
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var identity *Identity
+		switch a.mode {
+		case "none":
+			// Without authentication everyone can do everything
+			identity = &Identity{Username: "anonymous", Role: RoleAdmin}
+		case "basic":
+			username, password, ok := r.BasicAuth()
+			secret, matched := a.matchSecret(password)
+			if !ok || !matched || !constantTimeEqual(username, secret.username) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="Zep Admin", charset="UTF-8"`)
+				http.Error(w, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+			identity = &Identity{Username: secret.username, Role: secret.role}
+		default:
+			var err error
+			identity, err = a.readSession(r)
+			if err != nil {
+				a.requireLogin(w, r)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, identity)))
+	})
+}
+
+// requireLogin sends browsers to the login form and rejects everything else
+func (a *Authenticator) requireLogin(w http.ResponseWriter, r *http.Request) {
+	loginURL := a.loginPath + "?next=" + url.QueryEscape(r.URL.RequestURI())
+
+	// HTMX follows HX-Redirect instead of swapping the error into the page
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", loginURL)
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+		http.Redirect(w, r, loginURL, http.StatusFound)
+		return
+	}
+
+	http.Error(w, "Authentication required", http.StatusUnauthorized)
+}
+
+// PasswordLogin checks a shared secret from the login form and, if it
+// matches, issues a session cookie with that secret's role
+func (a *Authenticator) PasswordLogin(w http.ResponseWriter, password string) (*Identity, bool) {
+	secret, matched := a.matchSecret(password)
+	if !matched {
+		return nil, false
+	}
+
+	identity := Identity{Username: secret.username, Role: secret.role}
+	a.signIn(w, identity)
+	return &identity, true
+}
+
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func (a *Authenticator) signIn(w http.ResponseWriter, identity Identity) {
+	expiresAt := time.Now().Add(a.ttl)
+	a.setSigned(w, SessionCookie, session{Identity: identity, ExpiresAt: expiresAt.Unix()}, expiresAt)
+}
+
+// SignOut clears the session cookie
+func (a *Authenticator) SignOut(w http.ResponseWriter) {
+	a.clearCookie(w, SessionCookie)
+}
+
+func (a *Authenticator) readSession(r *http.Request) (*Identity, error) {
+	var s session
+	if err := a.readSigned(r, SessionCookie, &s); err != nil {
+		return nil, err
+	}
+	if s.Username == "" || roleRank[s.Role] == 0 || time.Now().Unix() >= s.ExpiresAt {
+		return nil, errors.New("session expired")
+	}
+	return &s.Identity, nil
+}
+
+// setSigned stores v as a signed, HTTP-only cookie
+func (a *Authenticator) setSigned(w http.ResponseWriter, name string, v interface{}, expiresAt time.Time) {
+	payload, _ := json.Marshal(v)
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    encoded + "." + a.sign(name, encoded),
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   a.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// readSigned decodes a cookie written by setSigned, rejecting tampered values
+func (a *Authenticator) readSigned(r *http.Request, name string, v interface{}) error {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return err
+	}
+
+	encoded, signature, found := strings.Cut(cookie.Value, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(a.sign(name, encoded))) {
+		return errors.New("invalid cookie signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, v)
+}
+
+func (a *Authenticator) clearCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   a.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// sign binds the value to the cookie name, so one signed cookie cannot be
+// replayed as another
+func (a *Authenticator) sign(name, value string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(name + "|" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SafeRedirect returns next if it is a local path, otherwise fallback
+func SafeRedirect(next, fallback string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return fallback
+	}
+	return next
+}
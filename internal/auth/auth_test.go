@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schizoidcock/zep-web-interface/internal/config"
+)
+
+func newTestAuthenticator(t *testing.T, secret string) *Authenticator {
+	t.Helper()
+	return New(&config.Config{
+		AuthMode:          "password",
+		AuthUsername:      "admin",
+		AuthPassword:      "admin-password",
+		AuthSessionSecret: secret,
+		AuthSessionTTL:    time.Hour,
+	}, "/admin")
+}
+
+// signedCookie returns the cookie a would set for v under name
+func signedCookie(a *Authenticator, name string, v interface{}) *http.Cookie {
+	rec := httptest.NewRecorder()
+	a.setSigned(rec, name, v, time.Now().Add(time.Hour))
+	return rec.Result().Cookies()[0]
+}
+
+func TestMiddlewareSessionCookie(t *testing.T) {
+	a := newTestAuthenticator(t, "test-secret")
+	other := newTestAuthenticator(t, "other-secret")
+
+	valid := session{Identity: Identity{Username: "admin", Role: RoleViewer}, ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	expired := valid
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	noRole := valid
+	noRole.Role = "root"
+
+	tests := []struct {
+		name   string
+		cookie func() *http.Cookie
+		want   int
+	}{
+		{"valid", func() *http.Cookie { return signedCookie(a, SessionCookie, valid) }, http.StatusOK},
+		{"missing", func() *http.Cookie { return nil }, http.StatusUnauthorized},
+		{"expired", func() *http.Cookie { return signedCookie(a, SessionCookie, expired) }, http.StatusUnauthorized},
+		{"unknown role", func() *http.Cookie { return signedCookie(a, SessionCookie, noRole) }, http.StatusUnauthorized},
+		{"other secret", func() *http.Cookie { return signedCookie(other, SessionCookie, valid) }, http.StatusUnauthorized},
+		{"tampered payload", func() *http.Cookie {
+			// Promote the viewer to admin but keep the viewer's signature
+			cookie := signedCookie(a, SessionCookie, valid)
+			promoted := valid
+			promoted.Role = RoleAdmin
+			_, signature, _ := strings.Cut(cookie.Value, ".")
+			payload, _, _ := strings.Cut(signedCookie(a, SessionCookie, promoted).Value, ".")
+			cookie.Value = payload + "." + signature
+			return cookie
+		}, http.StatusUnauthorized},
+		{"truncated signature", func() *http.Cookie {
+			cookie := signedCookie(a, SessionCookie, valid)
+			cookie.Value = cookie.Value[:len(cookie.Value)-1]
+			return cookie
+		}, http.StatusUnauthorized},
+		{"unsigned", func() *http.Cookie {
+			cookie := signedCookie(a, SessionCookie, valid)
+			cookie.Value, _, _ = strings.Cut(cookie.Value, ".")
+			return cookie
+		}, http.StatusUnauthorized},
+		{"replayed from another cookie", func() *http.Cookie {
+			cookie := signedCookie(a, oidcFlowCookie, valid)
+			cookie.Name = SessionCookie
+			return cookie
+		}, http.StatusUnauthorized},
+	}
+
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := FromContext(r.Context()); !ok {
+			t.Error("handler ran without an identity")
+		}
+	}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+			if cookie := tt.cookie(); cookie != nil {
+				req.AddCookie(cookie)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRedirectsBrowsersToLogin(t *testing.T) {
+	a := newTestAuthenticator(t, "test-secret")
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users?page=2", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusFound)
+	}
+	if got, want := rec.Header().Get("Location"), "/admin/login?next=%2Fadmin%2Fusers%3Fpage%3D2"; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+}
+
+func TestPasswordLoginIssuesWorkingSession(t *testing.T) {
+	a := newTestAuthenticator(t, "test-secret")
+
+	if _, ok := a.PasswordLogin(httptest.NewRecorder(), "wrong"); ok {
+		t.Fatal("wrong password signed in")
+	}
+
+	rec := httptest.NewRecorder()
+	identity, ok := a.PasswordLogin(rec, "admin-password")
+	if !ok || identity.Role != RoleAdmin {
+		t.Fatalf("PasswordLogin = %+v, %v; want an admin", identity, ok)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+	for _, cookie := range rec.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	got, err := a.readSession(req)
+	if err != nil {
+		t.Fatalf("readSession: %v", err)
+	}
+	if got.Username != "admin" || got.Role != RoleAdmin {
+		t.Errorf("session identity = %+v", got)
+	}
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"mime"
+	"net/http"
+)
+
+const (
+	// CSRFCookie holds the random value CSRF tokens are derived from
+	CSRFCookie = "zep_admin_csrf"
+	// CSRFHeader is how HTMX and fetch calls send the token
+	CSRFHeader = "X-CSRF-Token"
+	// CSRFField is how plain HTML forms send the token
+	CSRFField = "csrf_token"
+
+	// csrfFormLimit caps the body read to find CSRFField in a plain form
+	csrfFormLimit = 1 << 20
+)
+
+type csrfKey struct{}
+
+// CSRF issues a per-browser token and rejects state-changing requests that
+// do not echo it back. The token is an HMAC of an HTTP-only cookie, so
+// another site can neither read it nor forge one by planting a cookie.
+// Pages get it from CSRFToken; API clients can read it from the
+// X-CSRF-Token response header of any GET.
+func (a *Authenticator) CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce := ""
+		if cookie, err := r.Cookie(CSRFCookie); err == nil && cookie.Value != "" {
+			nonce = cookie.Value
+		} else {
+			b := make([]byte, 32)
+			rand.Read(b)
+			nonce = base64.RawURLEncoding.EncodeToString(b)
+			http.SetCookie(w, &http.Cookie{
+				Name:     CSRFCookie,
+				Value:    nonce,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   a.secure,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		token := a.sign(CSRFCookie, nonce)
+		w.Header().Set(CSRFHeader, token)
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		default:
+			sent := r.Header.Get(CSRFHeader)
+			if sent == "" {
+				sent = formCSRFToken(w, r)
+			}
+			if !constantTimeEqual(sent, token) {
+				http.Error(w, "Forbidden: missing or invalid CSRF token, reload the page and try again", http.StatusForbidden)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfKey{}, token)))
+	})
+}
+
+// CSRFToken returns the token templates embed in pages and forms
+func CSRFToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfKey{}).(string)
+	return token
+}
+
+// formCSRFToken reads CSRFField from a URL-encoded form body. Other bodies,
+// such as multipart uploads, are not parsed before the token is checked, so
+// they must send it in the X-CSRF-Token header.
+func formCSRFToken(w http.ResponseWriter, r *http.Request) string {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" {
+		return ""
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, csrfFormLimit)
+	return r.PostFormValue(CSRFField)
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	a := newTestAuthenticator(t, "test-secret")
+	const nonce = "browser-nonce"
+	token := a.sign(CSRFCookie, nonce)
+	otherToken := a.sign(CSRFCookie, "another-browser")
+
+	form := func(value string) (string, string) {
+		return "application/x-www-form-urlencoded", url.Values{CSRFField: {value}}.Encode()
+	}
+	multipartForm := func(value string) (string, string) {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField(CSRFField, value)
+		writer.Close()
+		return writer.FormDataContentType(), body.String()
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		header string
+		body   func() (string, string) // content type and body
+		want   int
+	}{
+		{name: "GET needs no token", method: http.MethodGet, want: http.StatusOK},
+		{name: "POST without token", method: http.MethodPost, want: http.StatusForbidden},
+		{name: "POST with header token", method: http.MethodPost, header: token, want: http.StatusOK},
+		{name: "DELETE with header token", method: http.MethodDelete, header: token, want: http.StatusOK},
+		{name: "DELETE without token", method: http.MethodDelete, want: http.StatusForbidden},
+		{name: "wrong header token", method: http.MethodPost, header: "not-a-token", want: http.StatusForbidden},
+		{name: "token of another browser", method: http.MethodPost, header: otherToken, want: http.StatusForbidden},
+		{name: "raw cookie value as token", method: http.MethodPost, header: nonce, want: http.StatusForbidden},
+		{name: "form field token", method: http.MethodPost, body: func() (string, string) { return form(token) }, want: http.StatusOK},
+		{name: "wrong form field token", method: http.MethodPost, body: func() (string, string) { return form(otherToken) }, want: http.StatusForbidden},
+		{name: "multipart field is not read", method: http.MethodPost, body: func() (string, string) { return multipartForm(token) }, want: http.StatusForbidden},
+		{name: "multipart with header token", method: http.MethodPost, header: token, body: func() (string, string) { return multipartForm("") }, want: http.StatusOK},
+		{name: "oversized form", method: http.MethodPost, body: func() (string, string) {
+			return "application/x-www-form-urlencoded", strings.Repeat("a=b&", csrfFormLimit/4+1) + CSRFField + "=" + url.QueryEscape(token)
+		}, want: http.StatusForbidden},
+	}
+
+	handler := a.CSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if CSRFToken(r) != token {
+			t.Errorf("CSRFToken = %q, want %q", CSRFToken(r), token)
+		}
+	}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			if tt.body != nil {
+				contentType, body := tt.body()
+				req = httptest.NewRequest(tt.method, "/admin/users", strings.NewReader(body))
+				req.Header.Set("Content-Type", contentType)
+			} else {
+				req = httptest.NewRequest(tt.method, "/admin/users", nil)
+			}
+			req.AddCookie(&http.Cookie{Name: CSRFCookie, Value: nonce})
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := rec.Header().Get(CSRFHeader); got != token {
+				t.Errorf("%s response header = %q, want %q", CSRFHeader, got, token)
+			}
+		})
+	}
+}
+
+func TestCSRFIssuesCookieOnFirstVisit(t *testing.T) {
+	a := newTestAuthenticator(t, "test-secret")
+	handler := a.CSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/", nil))
+
+	var nonce string
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == CSRFCookie {
+			nonce = cookie.Value
+			if !cookie.HttpOnly {
+				t.Error("CSRF cookie is readable from scripts")
+			}
+		}
+	}
+	if nonce == "" {
+		t.Fatal("no CSRF cookie issued")
+	}
+	if got, want := rec.Header().Get(CSRFHeader), a.sign(CSRFCookie, nonce); got != want {
+		t.Errorf("%s = %q, want the token for the issued cookie %q", CSRFHeader, got, want)
+	}
+
+	// A POST with no cookie gets a fresh one, which no token can match yet
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/admin/users", nil)
+	req.Header.Set(CSRFHeader, a.sign(CSRFCookie, nonce))
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("POST without cookie: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
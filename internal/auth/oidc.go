@@ -0,0 +1,537 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/schizoidcock/zep-web-interface/internal/config"
+)
+
+// oidcFlowCookie carries the state, nonce and PKCE verifier of a login in progress
+const oidcFlowCookie = "zep_admin_oidc"
+
+// oidcFlowTTL is how long the operator has to complete the provider's login
+const oidcFlowTTL = 10 * time.Minute
+
+// ErrAccessDenied means the provider authenticated someone who is not allowed in
+var ErrAccessDenied = errors.New("your account is not allowed to access this admin interface")
+
+// oidcDiscovery is the part of the provider metadata the login flow needs
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcFlow is the payload of the signed flow cookie
+type oidcFlow struct {
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	Next      string `json:"next"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// oidcProvider runs the authorization code flow with PKCE against an OpenID
+// Connect provider and decides who is allowed in
+type oidcProvider struct {
+	issuer         string
+	clientID       string
+	clientSecret   string
+	redirectURL    string
+	scopes         []string
+	allowedDomains []string
+	allowedGroups  []string
+	groupsClaim    string
+	admins         []string
+	operators      []string
+	httpClient     *http.Client
+
+	// Provider metadata and signing keys, fetched on first use
+	mutex     sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]crypto.PublicKey
+}
+
+func newOIDCProvider(cfg *config.Config) *oidcProvider {
+	return &oidcProvider{
+		issuer:         cfg.OIDCIssuerURL,
+		clientID:       cfg.OIDCClientID,
+		clientSecret:   cfg.OIDCClientSecret,
+		redirectURL:    cfg.OIDCRedirectURL,
+		scopes:         cfg.OIDCScopes,
+		allowedDomains: normalizeList(cfg.OIDCAllowedDomains),
+		allowedGroups:  normalizeList(cfg.OIDCAllowedGroups),
+		groupsClaim:    cfg.OIDCGroupsClaim,
+		admins:         normalizeList(cfg.OIDCAdmins),
+		operators:      normalizeList(cfg.OIDCOperators),
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func normalizeList(values []string) []string {
+	var normalized []string
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			normalized = append(normalized, value)
+		}
+	}
+	return normalized
+}
+
+// StartOIDCLogin redirects the browser to the provider's login page
+func (a *Authenticator) StartOIDCLogin(w http.ResponseWriter, r *http.Request, next string) error {
+	discovery, err := a.oidc.getDiscovery()
+	if err != nil {
+		return err
+	}
+
+	flow := oidcFlow{
+		State:     randomToken(),
+		Nonce:     randomToken(),
+		Verifier:  randomToken() + randomToken(),
+		Next:      next,
+		ExpiresAt: time.Now().Add(oidcFlowTTL).Unix(),
+	}
+	a.setSigned(w, oidcFlowCookie, flow, time.Unix(flow.ExpiresAt, 0))
+
+	challenge := sha256.Sum256([]byte(flow.Verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {a.oidc.clientID},
+		"redirect_uri":          {a.callbackURL(r)},
+		"scope":                 {strings.Join(a.oidc.scopes, " ")},
+		"state":                 {flow.State},
+		"nonce":                 {flow.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	http.Redirect(w, r, discovery.AuthorizationEndpoint+separator+params.Encode(), http.StatusFound)
+	return nil
+}
+
+// FinishOIDCLogin handles the provider's redirect back: it exchanges the code,
+// verifies the ID token, checks the allow-list and signs the operator in.
+// It returns where to send the browser next and who signed in, or who was
+// turned away when the allow-list rejects them.
+func (a *Authenticator) FinishOIDCLogin(w http.ResponseWriter, r *http.Request) (string, *Identity, error) {
+	var flow oidcFlow
+	if err := a.readSigned(r, oidcFlowCookie, &flow); err != nil {
+		return "", nil, fmt.Errorf("login expired, please try again")
+	}
+	a.clearCookie(w, oidcFlowCookie)
+
+	if time.Now().Unix() >= flow.ExpiresAt {
+		return "", nil, fmt.Errorf("login expired, please try again")
+	}
+
+	query := r.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		return "", nil, fmt.Errorf("identity provider error: %s %s", providerError, query.Get("error_description"))
+	}
+	if query.Get("state") == "" || query.Get("state") != flow.State {
+		return "", nil, fmt.Errorf("login state mismatch, please try again")
+	}
+
+	idToken, err := a.oidc.exchangeCode(query.Get("code"), flow.Verifier, a.callbackURL(r))
+	if err != nil {
+		return "", nil, err
+	}
+
+	claims, err := a.oidc.verifyIDToken(idToken, flow.Nonce)
+	if err != nil {
+		return "", nil, err
+	}
+
+	identity := a.oidc.identityFromClaims(claims)
+	if !a.oidc.allowed(identity) {
+		return "", &identity, fmt.Errorf("%w (%s)", ErrAccessDenied, identity.Display())
+	}
+	identity.Role = a.oidc.roleFor(identity)
+
+	a.signIn(w, identity)
+	return flow.Next, &identity, nil
+}
+
+// callbackURL is OIDC_REDIRECT_URL, or the callback route on the host the
+// request came in on. X-Forwarded-Proto is only believed behind a trusted
+// proxy, as anyone can send it.
+func (a *Authenticator) callbackURL(r *http.Request) string {
+	if a.oidc.redirectURL != "" {
+		return a.oidc.redirectURL
+	}
+
+	scheme := "http"
+	if r.TLS != nil || a.secure || (a.trustProxy && r.Header.Get("X-Forwarded-Proto") == "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + a.basePath + "/auth/callback"
+}
+
+func (p *oidcProvider) getDiscovery() (*oidcDiscovery, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := p.getJSON(p.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC discovery document: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("OIDC issuer mismatch: discovery says %s", discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery document is missing endpoints")
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+func (p *oidcProvider) exchangeCode(code, verifier, redirectURI string) (string, error) {
+	if code == "" {
+		return "", fmt.Errorf("identity provider did not return a code")
+	}
+
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {p.clientID},
+		"code_verifier": {verifier},
+	}
+	if p.clientSecret != "" {
+		form.Set("client_secret", p.clientSecret)
+	}
+
+	resp, err := p.httpClient.PostForm(discovery.TokenEndpoint, form)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if token.IDToken == "" {
+		return "", fmt.Errorf("token response has no id_token")
+	}
+	return token.IDToken, nil
+}
+
+// verifyIDToken checks the ID token's signature, issuer, audience, expiry
+// and nonce, and returns its claims
+func (p *oidcProvider) verifyIDToken(idToken, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed ID token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed ID token header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed ID token signature: %w", err)
+	}
+
+	key, err := p.signingKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(header.Alg, key, digest[:], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed ID token claims: %w", err)
+	}
+
+	if issuer, _ := claims["iss"].(string); strings.TrimSuffix(issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("ID token issuer mismatch: %s", issuer)
+	}
+	if !audienceContains(claims["aud"], p.clientID) {
+		return nil, fmt.Errorf("ID token was not issued for this client")
+	}
+	expiresAt, _ := claims["exp"].(float64)
+	if time.Now().Unix() >= int64(expiresAt) {
+		return nil, fmt.Errorf("ID token expired")
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("ID token nonce mismatch")
+	}
+	return claims, nil
+}
+
+// signingKey returns the provider key with the given ID, refetching the key
+// set once if the provider has rotated keys
+func (p *oidcProvider) signingKey(kid string) (crypto.PublicKey, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if key := p.findKey(kid); key != nil {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(discovery.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+
+	p.keys = make(map[string]crypto.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			p.keys[jwk.Kid] = key
+		}
+	}
+
+	if key := p.findKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no OIDC signing key matches the ID token")
+}
+
+// findKey looks up a cached key; tokens without a key ID match a single key
+func (p *oidcProvider) findKey(kid string) crypto.PublicKey {
+	if key, exists := p.keys[kid]; exists {
+		return key
+	}
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// identityFromClaims reads the operator from the ID token. An email the
+// provider says is unverified is dropped, so it cannot match an allowed
+// domain or a role list.
+func (p *oidcProvider) identityFromClaims(claims map[string]interface{}) Identity {
+	identity := Identity{
+		Email:  stringClaim(claims, "email"),
+		Name:   stringClaim(claims, "name"),
+		Groups: stringsClaim(claims, p.groupsClaim),
+	}
+	if verified, present := claims["email_verified"].(bool); present && !verified {
+		identity.Email = ""
+	}
+
+	identity.Username = stringClaim(claims, "preferred_username")
+	if identity.Username == "" {
+		identity.Username = identity.Email
+	}
+	if identity.Username == "" {
+		identity.Username = stringClaim(claims, "sub")
+	}
+	return identity
+}
+
+// allowed checks the identity against the configured email domains and groups
+func (p *oidcProvider) allowed(identity Identity) bool {
+	if identity.Email != "" {
+		_, domain, _ := strings.Cut(strings.ToLower(identity.Email), "@")
+		for _, allowed := range p.allowedDomains {
+			if domain == strings.ToLower(strings.TrimPrefix(allowed, "@")) {
+				return true
+			}
+		}
+	}
+
+	for _, group := range identity.Groups {
+		for _, allowed := range p.allowedGroups {
+			if group == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *oidcProvider) getJSON(target string, v interface{}) error {
+	resp, err := p.httpClient.Get(target)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// jsonWebKey is an RSA or P-256 public key from the provider's key set
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func verifySignature(alg string, key crypto.PublicKey, digest, signature []byte) error {
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("ID token key does not match RS256")
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest, signature); err != nil {
+			return fmt.Errorf("invalid ID token signature")
+		}
+		return nil
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return fmt.Errorf("ID token key does not match ES256")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return fmt.Errorf("invalid ID token signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported ID token algorithm %s", alg)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func audienceContains(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, value := range aud {
+			if value == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// stringsClaim reads a claim that may be a single string or a list of strings
+func stringsClaim(claims map[string]interface{}, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schizoidcock/zep-web-interface/internal/config"
+)
+
+const testClientID = "zep-admin"
+
+// newTestProvider serves discovery and a key set holding key as "k1"
+func newTestProvider(t *testing.T, key *rsa.PrivateKey) *oidcProvider {
+	t.Helper()
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                issuer,
+			AuthorizationEndpoint: issuer + "/authorize",
+			TokenEndpoint:         issuer + "/token",
+			JWKSURI:               issuer + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []jsonWebKey{{
+				Kty: "RSA",
+				Kid: "k1",
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	issuer = server.URL
+
+	return newOIDCProvider(&config.Config{OIDCIssuerURL: issuer, OIDCClientID: testClientID})
+}
+
+// signToken builds an RS256 ID token
+func signToken(t *testing.T, key *rsa.PrivateKey, header, claims map[string]interface{}) string {
+	t.Helper()
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newTestProvider(t, key)
+
+	header := func() map[string]interface{} {
+		return map[string]interface{}{"alg": "RS256", "kid": "k1"}
+	}
+	claims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   p.issuer,
+			"aud":   testClientID,
+			"sub":   "user-1",
+			"email": "ann@example.com",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "expected-nonce",
+		}
+	}
+	with := func(name string, value interface{}) map[string]interface{} {
+		c := claims()
+		c[name] = value
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr string // empty when the token must be accepted
+	}{
+		{"valid", func() string { return signToken(t, key, header(), claims()) }, ""},
+		{"audience list", func() string {
+			return signToken(t, key, header(), with("aud", []string{"another-client", testClientID}))
+		}, ""},
+		{"issuer with trailing slash", func() string { return signToken(t, key, header(), with("iss", p.issuer+"/")) }, ""},
+		{"bad signature", func() string { return signToken(t, otherKey, header(), claims()) }, "invalid ID token signature"},
+		{"claims swapped after signing", func() string {
+			token := signToken(t, key, header(), claims())
+			forged := strings.Split(signToken(t, key, header(), with("email", "mallory@example.com")), ".")
+			parts := strings.Split(token, ".")
+			return parts[0] + "." + forged[1] + "." + parts[2]
+		}, "invalid ID token signature"},
+		{"unsigned", func() string {
+			h := header()
+			h["alg"] = "none"
+			parts := strings.Split(signToken(t, key, h, claims()), ".")
+			return parts[0] + "." + parts[1] + "."
+		}, "unsupported ID token algorithm"},
+		{"unknown key", func() string {
+			h := header()
+			h["kid"] = "k2"
+			return signToken(t, key, h, claims())
+		}, "no OIDC signing key"},
+		{"malformed", func() string { return "not-a-token" }, "malformed ID token"},
+		{"wrong audience", func() string { return signToken(t, key, header(), with("aud", "another-client")) }, "not issued for this client"},
+		{"audience list without client", func() string {
+			return signToken(t, key, header(), with("aud", []string{"another-client"}))
+		}, "not issued for this client"},
+		{"missing audience", func() string {
+			c := claims()
+			delete(c, "aud")
+			return signToken(t, key, header(), c)
+		}, "not issued for this client"},
+		{"wrong issuer", func() string { return signToken(t, key, header(), with("iss", "https://evil.example.com")) }, "issuer mismatch"},
+		{"expired", func() string { return signToken(t, key, header(), with("exp", time.Now().Add(-time.Minute).Unix())) }, "expired"},
+		{"missing expiry", func() string {
+			c := claims()
+			delete(c, "exp")
+			return signToken(t, key, header(), c)
+		}, "expired"},
+		{"nonce mismatch", func() string { return signToken(t, key, header(), with("nonce", "replayed-nonce")) }, "nonce mismatch"},
+		{"missing nonce", func() string {
+			c := claims()
+			delete(c, "nonce")
+			return signToken(t, key, header(), c)
+		}, "nonce mismatch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.verifyIDToken(tt.token(), "expected-nonce")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyIDToken: %v", err)
+				}
+				if got["sub"] != "user-1" {
+					t.Errorf("sub = %v, want user-1", got["sub"])
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("verifyIDToken accepted the token, want an error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCallbackURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		forwarded  string
+		want       string
+	}{
+		{"plain http", false, "", "http://admin.example.com/admin/auth/callback"},
+		{"untrusted forwarded proto", false, "https", "http://admin.example.com/admin/auth/callback"},
+		{"trusted forwarded proto", true, "https", "https://admin.example.com/admin/auth/callback"},
+		{"trusted proxy on http", true, "http", "http://admin.example.com/admin/auth/callback"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(&config.Config{
+				AuthMode:          "oidc",
+				AuthSessionSecret: "test-secret",
+				TrustProxy:        tt.trustProxy,
+			}, "/admin")
+			req := httptest.NewRequest(http.MethodGet, "http://admin.example.com/admin/login", nil)
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.forwarded)
+			}
+			if got := a.callbackURL(req); got != tt.want {
+				t.Errorf("callbackURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
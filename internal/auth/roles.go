@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Role is what a signed-in operator may do
+type Role string
+
+const (
+	// RoleViewer can browse users, sessions and graphs
+	RoleViewer Role = "viewer"
+	// RoleOperator can also create and edit users
+	RoleOperator Role = "operator"
+	// RoleAdmin can also delete users and sessions
+	RoleAdmin Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Allows reports whether the role includes the required role
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+// RequireRole rejects requests from operators without the required role.
+// It must run after Middleware.
+func (a *Authenticator) RequireRole(required Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := FromContext(r.Context())
+			if !ok || !identity.Role.Allows(required) {
+				http.Error(w, "Forbidden: this action requires the "+string(required)+" role", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// sharedSecret is a password that signs in with a fixed role
+type sharedSecret struct {
+	username string
+	password string
+	role     Role
+}
+
+// matchSecret returns the shared secret the password belongs to. Every
+// configured secret is compared so timing does not reveal which one matched.
+func (a *Authenticator) matchSecret(password string) (sharedSecret, bool) {
+	var matched sharedSecret
+	found := false
+	for _, secret := range a.secrets {
+		if secret.password != "" && constantTimeEqual(password, secret.password) && !found {
+			matched = secret
+			found = true
+		}
+	}
+	return matched, found
+}
+
+// roleFor picks the OIDC identity's role from the admin and operator lists,
+// which hold emails or group names. Everyone else allowed in is a viewer.
+func (p *oidcProvider) roleFor(identity Identity) Role {
+	if matchesAny(identity, p.admins) {
+		return RoleAdmin
+	}
+	if matchesAny(identity, p.operators) {
+		return RoleOperator
+	}
+	return RoleViewer
+}
+
+func matchesAny(identity Identity, entries []string) bool {
+	for _, entry := range entries {
+		if identity.Email != "" && strings.EqualFold(entry, identity.Email) {
+			return true
+		}
+		for _, group := range identity.Groups {
+			if entry == group {
+				return true
+			}
+		}
+	}
+	return false
+}
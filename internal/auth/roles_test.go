@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/schizoidcock/zep-web-interface/internal/config"
+)
+
+func TestRequireRole(t *testing.T) {
+	a := newTestAuthenticator(t, "test-secret")
+
+	tests := []struct {
+		name     string
+		identity *Identity // nil when Middleware attached none
+		required Role
+		want     int
+	}{
+		{"viewer may view", &Identity{Username: "v", Role: RoleViewer}, RoleViewer, http.StatusOK},
+		{"viewer may not edit", &Identity{Username: "v", Role: RoleViewer}, RoleOperator, http.StatusForbidden},
+		{"viewer may not delete", &Identity{Username: "v", Role: RoleViewer}, RoleAdmin, http.StatusForbidden},
+		{"operator may edit", &Identity{Username: "o", Role: RoleOperator}, RoleOperator, http.StatusOK},
+		{"operator may not delete", &Identity{Username: "o", Role: RoleOperator}, RoleAdmin, http.StatusForbidden},
+		{"admin may delete", &Identity{Username: "a", Role: RoleAdmin}, RoleAdmin, http.StatusOK},
+		{"unknown role", &Identity{Username: "x", Role: "root"}, RoleViewer, http.StatusForbidden},
+		{"no identity", nil, RoleViewer, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := a.RequireRole(tt.required)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/users", nil)
+			if tt.identity != nil {
+				req = req.WithContext(context.WithValue(req.Context(), contextKey{}, tt.identity))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if called != (tt.want == http.StatusOK) {
+				t.Errorf("handler called = %v", called)
+			}
+		})
+	}
+}
+
+func TestSharedSecretRoles(t *testing.T) {
+	a := New(&config.Config{
+		AuthMode:             "password",
+		AuthUsername:         "admin",
+		AuthPassword:         "admin-password",
+		AuthOperatorPassword: "operator-password",
+		AuthSessionSecret:    "test-secret",
+	}, "/admin")
+
+	tests := []struct {
+		password string
+		want     Role
+		ok       bool
+	}{
+		{"admin-password", RoleAdmin, true},
+		{"operator-password", RoleOperator, true},
+		{"viewer-password", "", false}, // not configured
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		secret, ok := a.matchSecret(tt.password)
+		if ok != tt.ok || secret.role != tt.want {
+			t.Errorf("matchSecret(%q) = %q, %v; want %q, %v", tt.password, secret.role, ok, tt.want, tt.ok)
+		}
+	}
+}
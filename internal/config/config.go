@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -25,6 +26,35 @@ type Config struct {
 	FalkorDBBrowserURL     string
 	HybridProxyURL         string
 	ZepServerURL           string
+
+	// Authentication for the admin UI and API
+	AuthMode          string // "none", "basic", "password" or "oidc"
+	AuthUsername      string
+	AuthPassword      string
+	AuthSessionSecret string
+	AuthSessionTTL    time.Duration
+
+	// Extra shared secrets that sign in with a lesser role
+	AuthOperatorPassword string
+	AuthViewerPassword   string
+
+	// OpenID Connect single sign-on (AUTH_MODE=oidc)
+	OIDCIssuerURL      string
+	OIDCClientID       string
+	OIDCClientSecret   string
+	OIDCRedirectURL    string
+	OIDCScopes         []string
+	OIDCAllowedDomains []string
+	OIDCAllowedGroups  []string
+	OIDCGroupsClaim    string
+
+	// Emails or groups granted the admin and operator roles; everyone else
+	// allowed in through OIDC is a viewer
+	OIDCAdmins    []string
+	OIDCOperators []string
+
+	// Append-only audit trail of mutating admin actions (JSON lines)
+	AuditLogPath string
 }
 
 func Load() *Config {
@@ -67,6 +97,35 @@ func Load() *Config {
 		FalkorDBBrowserURL:     getEnv("FALKORDB_BROWSER_URL", ""),
 		HybridProxyURL:         getEnv("HYBRID_PROXY_URL", ""),
 		ZepServerURL:           getEnv("ZEP_SERVER_URL", ""),
+
+		// Authentication - a password alone enables the login form
+		AuthMode:          strings.ToLower(getEnv("AUTH_MODE", "")),
+		AuthUsername:      getEnv("AUTH_USERNAME", "admin"),
+		AuthPassword:      getEnv("AUTH_PASSWORD", ""),
+		AuthSessionSecret: getEnv("AUTH_SESSION_SECRET", ""),
+		AuthSessionTTL:    getEnvDuration("AUTH_SESSION_TTL", 12*time.Hour),
+
+		AuthOperatorPassword: getEnv("AUTH_OPERATOR_PASSWORD", ""),
+		AuthViewerPassword:   getEnv("AUTH_VIEWER_PASSWORD", ""),
+
+		// OpenID Connect - access is limited to allowed domains or groups
+		OIDCIssuerURL:      strings.TrimSuffix(getEnv("OIDC_ISSUER_URL", ""), "/"),
+		OIDCClientID:       getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:   getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:    getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:         getEnvSlice("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+		OIDCAllowedDomains: getEnvSlice("OIDC_ALLOWED_DOMAINS", nil),
+		OIDCAllowedGroups:  getEnvSlice("OIDC_ALLOWED_GROUPS", nil),
+		OIDCGroupsClaim:    getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCAdmins:         getEnvSlice("OIDC_ADMINS", nil),
+		OIDCOperators:      getEnvSlice("OIDC_OPERATORS", nil),
+
+		AuditLogPath: getEnv("AUDIT_LOG_PATH", "data/audit.log"),
+	}
+	// A password alone means the login form; leaving the UI open has to be
+	// asked for with AUTH_MODE=none
+	if cfg.AuthMode == "" && cfg.AuthPassword != "" {
+		cfg.AuthMode = "password"
 	}
 	
 	// Debug logging for API key (show first/last 8 chars for security)
@@ -135,6 +194,45 @@ func (c *Config) validate() error {
 		}
 	}
 	
+	// Validate authentication
+	switch c.AuthMode {
+	case "":
+		return fmt.Errorf("no authentication configured: set AUTH_PASSWORD, or AUTH_MODE to basic, password or oidc (AUTH_MODE=none disables authentication)")
+	case "none":
+	case "basic", "password":
+		if c.AuthPassword == "" {
+			return fmt.Errorf("AUTH_PASSWORD is required when AUTH_MODE=%s", c.AuthMode)
+		}
+		if c.AuthUsername == "" {
+			return fmt.Errorf("AUTH_USERNAME cannot be empty when AUTH_MODE=%s", c.AuthMode)
+		}
+
+		// Each shared secret decides the role, so they must differ
+		secrets := map[string]bool{c.AuthPassword: true}
+		for _, password := range []string{c.AuthOperatorPassword, c.AuthViewerPassword} {
+			if password != "" && secrets[password] {
+				return fmt.Errorf("AUTH_PASSWORD, AUTH_OPERATOR_PASSWORD and AUTH_VIEWER_PASSWORD must all differ")
+			}
+			secrets[password] = true
+		}
+	case "oidc":
+		if c.OIDCIssuerURL == "" || c.OIDCClientID == "" {
+			return fmt.Errorf("OIDC_ISSUER_URL and OIDC_CLIENT_ID are required when AUTH_MODE=oidc")
+		}
+		if _, err := url.Parse(c.OIDCIssuerURL); err != nil {
+			return fmt.Errorf("OIDC_ISSUER_URL is not a valid URL: %v", err)
+		}
+		if len(c.OIDCAllowedDomains) == 0 && len(c.OIDCAllowedGroups) == 0 {
+			return fmt.Errorf("OIDC_ALLOWED_DOMAINS or OIDC_ALLOWED_GROUPS is required when AUTH_MODE=oidc")
+		}
+	default:
+		return fmt.Errorf("AUTH_MODE must be none, basic, password or oidc, got: %s", c.AuthMode)
+	}
+
+	if c.AuthSessionTTL <= 0 {
+		return fmt.Errorf("AUTH_SESSION_TTL must be positive, got: %s", c.AuthSessionTTL)
+	}
+
 	return nil
 }
 
@@ -163,6 +261,15 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if durationValue, err := time.ParseDuration(value); err == nil {
+			return durationValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		return strings.Split(value, ",")
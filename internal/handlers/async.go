@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -14,10 +15,55 @@ import (
 
 // AsyncData represents data loading status for async endpoints
 type AsyncData struct {
-	Status  string      `json:"status"`  // "loading", "success", "error"
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
-	Progress int        `json:"progress,omitempty"` // 0-100
+	Status    string      `json:"status"`  // "loading", "success", "error"
+	Data      interface{} `json:"data,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Progress  int         `json:"progress"` // 0-100
+	Message   string      `json:"message,omitempty"`
+	Completed int         `json:"completed,omitempty"` // units of work done so far
+	Total     int         `json:"total,omitempty"`     // units of work in total, once known
+}
+
+// asyncProgress tracks a running background load so that polls can report
+// how much work has actually been done
+type asyncProgress struct {
+	mu        sync.Mutex
+	message   string
+	completed int
+	total     int
+}
+
+func newAsyncProgress(message string) *asyncProgress {
+	return &asyncProgress{message: message}
+}
+
+// update records the current stage and work counts
+func (p *asyncProgress) update(message string, completed, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.message = message
+	p.completed = completed
+	p.total = total
+}
+
+// snapshot returns the loading status as seen by a poll
+func (p *asyncProgress) snapshot() AsyncData {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	progress := 0
+	if p.total > 0 {
+		progress = p.completed * 100 / p.total
+	}
+
+	return AsyncData{
+		Status:    "loading",
+		Progress:  progress,
+		Message:   p.message,
+		Completed: p.completed,
+		Total:     p.total,
+	}
 }
 
 // BackgroundProcessor handles async data loading
@@ -33,119 +79,250 @@ func NewBackgroundProcessor(apiClient *zepapi.Client) *BackgroundProcessor {
 	}
 }
 
-// UserGraphAsync handles async user graph loading
+// writeAsyncData writes an async status response as JSON
+func writeAsyncData(w http.ResponseWriter, data AsyncData) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+// UserGraphAsync handles async user graph loading. The first call starts the
+// load in the background; later calls report progress until the graph is
+// cached, at which point it is returned with a "success" status.
 func (h *Handlers) UserGraphAsync(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userId")
 	cacheKey := fmt.Sprintf("graph:%s", userID)
-	
-	// Check if data is already cached
+	errorKey := fmt.Sprintf("graph:error:%s", userID)
+
+	// Check if data is already cached (shared with the HTML graph handlers)
 	if cached, found := h.cache.Get(cacheKey); found {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(cached)
-		return
+		if graph, ok := cached.(*zepapi.Graph); ok {
+			writeAsyncData(w, AsyncData{
+				Status:   "success",
+				Data:     graph,
+				Progress: 100,
+			})
+			return
+		}
+	}
+
+	// Report a recent failure instead of hammering the API again
+	if cached, found := h.cache.Get(errorKey); found {
+		if failed, ok := cached.(AsyncData); ok {
+			writeAsyncData(w, failed)
+			return
+		}
+	}
+
+	// Start the load, or report on the one already running
+	progress := h.startGraphLoad(userID)
+	writeAsyncData(w, progress.snapshot())
+}
+
+// partialGraphTTL is how long a graph missing failed episodes stays cached,
+// so the next visit soon retries them
+const partialGraphTTL = 2 * time.Minute
+
+// graphCacheTTL is how long to cache a loaded graph: ttl when it is
+// complete, otherwise partialGraphTTL
+func graphCacheTTL(graph *zepapi.Graph, ttl time.Duration) time.Duration {
+	if graph.Partial() {
+		return partialGraphTTL
 	}
-	
+	return ttl
+}
+
+// graphLoadsMu stops two requests from both starting a load for a user
+var graphLoadsMu sync.Mutex
+
+// startGraphLoad loads a user's graph into the cache in the background and
+// returns its progress. A load already running for the user is joined
+// rather than started again.
+func (h *Handlers) startGraphLoad(userID string) *asyncProgress {
+	cacheKey := fmt.Sprintf("graph:%s", userID)
+	errorKey := fmt.Sprintf("graph:error:%s", userID)
+
+	graphLoadsMu.Lock()
+	defer graphLoadsMu.Unlock()
+
 	// Check if loading is in progress
 	loadingKey := fmt.Sprintf("graph:loading:%s", userID)
-	if _, loading := h.cache.Get(loadingKey); loading {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(AsyncData{
-			Status:   "loading",
-			Progress: 50, // Estimated progress
-		})
-		return
+	if cached, loading := h.cache.Get(loadingKey); loading {
+		if progress, ok := cached.(*asyncProgress); ok {
+			return progress
+		}
 	}
-	
+
 	// Start background loading
-	h.cache.Set(loadingKey, true, 10*time.Minute)
-	
+	progress := newAsyncProgress("Fetching episodes...")
+	h.cache.Set(loadingKey, progress, 10*time.Minute)
+
 	go func() {
 		defer h.cache.Delete(loadingKey)
-		
+
 		log.Printf("🚀 Starting background graph load for user: %s", userID)
-		
-		triplets, err := h.apiClient.GetUserGraphTriplets(userID)
+
+		graph, err := h.apiClient.GetUserGraphWithProgress(userID, func(completed, total int) {
+			progress.update(fmt.Sprintf("Loaded mentions for %d of %d episodes", completed, total), completed, total)
+		})
 		if err != nil {
 			log.Printf("❌ Graph load failed for user %s: %v", userID, err)
-			h.cache.Set(cacheKey, AsyncData{
+			h.cache.Set(errorKey, AsyncData{
 				Status: "error",
 				Error:  err.Error(),
 			}, 5*time.Minute)
 			return
 		}
-		
-		log.Printf("✅ Graph load completed for user %s: %d triplets", userID, len(triplets))
-		h.cache.Set(cacheKey, AsyncData{
-			Status: "success",
-			Data:   triplets,
-		}, 30*time.Minute) // Cache for 30 minutes
+
+		log.Printf("✅ Graph load completed for user %s: %d nodes, %d edges, %d failed episodes", userID, len(graph.Nodes), len(graph.Edges), len(graph.FailedEpisodes))
+		h.cache.Set(cacheKey, graph, graphCacheTTL(graph, 30*time.Minute))
 	}()
-	
-	// Return loading status immediately
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(AsyncData{
-		Status:   "loading",
-		Progress: 10,
+
+	return progress
+}
+
+// graphStreamEvent is one line of a graph stream. Type is "start" once the
+// episode count is known, "episode" for each episode's mentions, then
+// "done", or "error" if the load failed. The "done" event lists the episodes
+// that failed to load.
+type graphStreamEvent struct {
+	Type string `json:"type"`
+	zepapi.GraphChunk
+	FailedEpisodes []string `json:"failed_episodes,omitempty"`
+}
+
+// UserGraphStream streams a user's graph as NDJSON, one line per episode as
+// its mentions arrive, so the page can draw the graph while it loads. The
+// upstream calls stop when the client goes away. A finished load is cached
+// for the graph page and the async endpoint, briefly if it is partial.
+func (h *Handlers) UserGraphStream(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userId")
+	cacheKey := fmt.Sprintf("graph:%s", userID)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stop proxies such as nginx from holding the lines back
+	w.Header().Set("X-Accel-Buffering", "no")
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	send := func(event graphStreamEvent) error {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	// A cached graph goes out in one chunk
+	if cached, found := h.cache.Get(cacheKey); found {
+		if graph, ok := cached.(*zepapi.Graph); ok {
+			chunk := zepapi.GraphChunk{Nodes: graph.Nodes, Edges: graph.Edges, Completed: 1, Total: 1}
+			if send(graphStreamEvent{Type: "start", GraphChunk: zepapi.GraphChunk{Total: 1}}) == nil &&
+				send(graphStreamEvent{Type: "episode", GraphChunk: chunk}) == nil {
+				send(graphStreamEvent{Type: "done", GraphChunk: zepapi.GraphChunk{Completed: 1, Total: 1}, FailedEpisodes: graph.FailedEpisodes})
+			}
+			return
+		}
+	}
+
+	log.Printf("🚀 Streaming graph for user: %s", userID)
+	builder := zepapi.NewGraphBuilder()
+	var last zepapi.GraphChunk
+	err := h.apiClient.StreamUserGraph(r.Context(), userID, func(chunk zepapi.GraphChunk) error {
+		builder.Add(chunk)
+		last = chunk
+		eventType := "episode"
+		if chunk.EpisodeID == "" {
+			eventType = "start"
+		}
+		return send(graphStreamEvent{Type: eventType, GraphChunk: chunk})
 	})
+	if err != nil {
+		if r.Context().Err() != nil {
+			log.Printf("⏹️ Graph stream for user %s cancelled after %d of %d episodes", userID, last.Completed, last.Total)
+			return
+		}
+		log.Printf("❌ Graph stream failed for user %s: %v", userID, err)
+		send(graphStreamEvent{Type: "error", GraphChunk: zepapi.GraphChunk{Error: err.Error()}})
+		return
+	}
+
+	graph := builder.Graph()
+	if graph.Partial() {
+		log.Printf("⚠️ Graph stream for user %s finished without %d failed episodes", userID, len(graph.FailedEpisodes))
+	}
+	h.cache.Set(cacheKey, graph, graphCacheTTL(graph, 30*time.Minute))
+	send(graphStreamEvent{Type: "done", GraphChunk: zepapi.GraphChunk{Completed: last.Completed, Total: last.Total}, FailedEpisodes: graph.FailedEpisodes})
 }
 
-// UserEpisodesAsync handles async user episodes loading
+// UserEpisodesAsync handles async user episodes loading, following the same
+// start-then-poll protocol as UserGraphAsync
 func (h *Handlers) UserEpisodesAsync(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userId")
 	cacheKey := fmt.Sprintf("episodes:%s", userID)
-	
-	// Check if data is already cached
+	errorKey := fmt.Sprintf("episodes:error:%s", userID)
+
+	// Check if data is already cached (shared with the HTML episodes handlers)
 	if cached, found := h.cache.Get(cacheKey); found {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(cached)
-		return
+		if episodes, ok := cached.([]zepapi.Episode); ok {
+			writeAsyncData(w, AsyncData{
+				Status:    "success",
+				Data:      episodes,
+				Progress:  100,
+				Completed: len(episodes),
+				Total:     len(episodes),
+			})
+			return
+		}
+	}
+
+	// Report a recent failure instead of hammering the API again
+	if cached, found := h.cache.Get(errorKey); found {
+		if failed, ok := cached.(AsyncData); ok {
+			writeAsyncData(w, failed)
+			return
+		}
 	}
-	
+
 	// Check if loading is in progress
 	loadingKey := fmt.Sprintf("episodes:loading:%s", userID)
-	if _, loading := h.cache.Get(loadingKey); loading {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(AsyncData{
-			Status:   "loading",
-			Progress: 70,
-		})
-		return
+	if cached, loading := h.cache.Get(loadingKey); loading {
+		if progress, ok := cached.(*asyncProgress); ok {
+			writeAsyncData(w, progress.snapshot())
+			return
+		}
 	}
-	
+
 	// Start background loading
-	h.cache.Set(loadingKey, true, 5*time.Minute)
-	
+	progress := newAsyncProgress("Fetching episodes...")
+	h.cache.Set(loadingKey, progress, 5*time.Minute)
+
 	go func() {
 		defer h.cache.Delete(loadingKey)
-		
+
 		log.Printf("🚀 Starting background episodes load for user: %s", userID)
-		
+
 		episodes, err := h.apiClient.GetUserEpisodes(userID)
 		if err != nil {
 			log.Printf("❌ Episodes load failed for user %s: %v", userID, err)
-			h.cache.Set(cacheKey, AsyncData{
+			h.cache.Set(errorKey, AsyncData{
 				Status: "error",
 				Error:  err.Error(),
 			}, 2*time.Minute)
 			return
 		}
-		
+
 		log.Printf("✅ Episodes load completed for user %s: %d episodes", userID, len(episodes))
-		h.cache.Set(cacheKey, AsyncData{
-			Status: "success",
-			Data:   episodes,
-		}, 15*time.Minute) // Cache for 15 minutes
+		progress.update(fmt.Sprintf("Fetched %d episodes", len(episodes)), len(episodes), len(episodes))
+		h.cache.Set(cacheKey, episodes, 15*time.Minute) // Cache for 15 minutes
 	}()
-	
+
 	// Return loading status immediately
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(AsyncData{
-		Status:   "loading",
-		Progress: 20,
-	})
+	writeAsyncData(w, progress.snapshot())
 }
 
 // Add cache to main handlers struct
 func (h *Handlers) SetCache(c *cache.Cache) {
 	h.cache = c
-}
\ No newline at end of file
+}
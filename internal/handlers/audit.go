@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/schizoidcock/zep-web-interface/internal/audit"
+	"github.com/schizoidcock/zep-web-interface/internal/auth"
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+// auditPageSize is how many entries the Audit page shows at once
+const auditPageSize = 50
+
+// auditActions are offered in the Audit page's action filter
+var auditActions = []string{
+	"user.create",
+	"user.update",
+	"user.delete",
+	"user.export",
+	"session.delete",
+	"auth.login",
+}
+
+// auditEntry starts an entry for an action taken by the request's operator.
+// The client IP is the one chi's RealIP middleware settled on when
+// TRUST_PROXY is enabled, otherwise the peer address.
+func (h *Handlers) auditEntry(r *http.Request, action, targetType, targetID string) audit.Entry {
+	entry := audit.Entry{
+		Time:       time.Now().UTC(),
+		ClientIP:   clientIP(r),
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+	if identity, ok := auth.FromContext(r.Context()); ok {
+		entry.Actor = auditActor(identity)
+		entry.Role = string(identity.Role)
+	}
+	return entry
+}
+
+// auditActor names an operator by email when single sign-on provides one,
+// since display names are not unique
+func auditActor(identity *auth.Identity) string {
+	if identity.Email != "" {
+		return identity.Email
+	}
+	return identity.Username
+}
+
+// recordAudit appends the entry with its outcome taken from err, unless the
+// caller already set one. A failure to write is logged but never undoes or
+// blocks the action itself.
+func (h *Handlers) recordAudit(entry audit.Entry, err error) {
+	if h.auditLog == nil {
+		return
+	}
+	if entry.Outcome == "" {
+		entry.Outcome = audit.OutcomeSuccess
+		if err != nil {
+			entry.Outcome = audit.OutcomeFailure
+		}
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if writeErr := h.auditLog.Record(entry); writeErr != nil {
+		log.Printf("❌ Failed to record audit entry %s %s: %v", entry.Action, entry.TargetID, writeErr)
+	}
+}
+
+// recordDeletionAudit records a user deletion, marking sessions left behind as partial
+func (h *Handlers) recordDeletionAudit(entry audit.Entry, result *zepapi.UserDeletionResult, err error) {
+	if result != nil {
+		entry.Details = result
+		if result.Partial() {
+			entry.Outcome = audit.OutcomePartial
+		}
+	}
+	h.recordAudit(entry, err)
+}
+
+// userSnapshot is the part of a user an update can change
+func userSnapshot(user *zepapi.User) map[string]interface{} {
+	if user == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"email":      user.Email,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"metadata":   user.Metadata,
+	}
+}
+
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// auditFilter reads the Audit page filters from the query string. Dates
+// are whole days, and the until date is inclusive.
+func auditFilter(query url.Values) (audit.Filter, error) {
+	filter := audit.Filter{
+		Actor:    query.Get("actor"),
+		Action:   query.Get("action"),
+		TargetID: query.Get("target"),
+		Outcome:  query.Get("outcome"),
+	}
+
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse("2006-01-02", since)
+		if err != nil {
+			return filter, fmt.Errorf("since must be a date like 2006-01-02")
+		}
+		filter.Since = t
+	}
+	if until := query.Get("until"); until != "" {
+		t, err := time.Parse("2006-01-02", until)
+		if err != nil {
+			return filter, fmt.Errorf("until must be a date like 2006-01-02")
+		}
+		filter.Until = t.AddDate(0, 0, 1)
+	}
+	return filter, nil
+}
+
+// AuditLog renders the filterable audit trail, newest first
+func (h *Handlers) AuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := auditFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.auditLog.Query(filter)
+	if err != nil {
+		log.Printf("❌ Failed to read audit log: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageCount := (len(entries) + auditPageSize - 1) / auditPageSize
+	if pageCount == 0 {
+		pageCount = 1
+	}
+	if page < 1 {
+		page = 1
+	}
+	if page > pageCount {
+		page = pageCount
+	}
+	start := (page - 1) * auditPageSize
+	end := start + auditPageSize
+	if end > len(entries) {
+		end = len(entries)
+	}
+
+	// Pager and export links keep the current filters
+	filterQuery := url.Values{}
+	for _, key := range []string{"actor", "action", "target", "outcome", "since", "until"} {
+		if value := query.Get(key); value != "" {
+			filterQuery.Set(key, value)
+		}
+	}
+	pageURL := func(n int) string {
+		q := url.Values{}
+		for key, values := range filterQuery {
+			q[key] = values
+		}
+		q.Set("page", strconv.Itoa(n))
+		return r.URL.Path + "?" + q.Encode()
+	}
+
+	exportURL := h.basePath + "/api/audit?download=1"
+	if encoded := filterQuery.Encode(); encoded != "" {
+		exportURL += "&" + encoded
+	}
+
+	data := map[string]interface{}{
+		"Title":    "Audit Log",
+		"SubTitle": "Every change made through the admin, newest first",
+		"Page":     "audit",
+		"Path":     r.URL.Path,
+		"BreadCrumbs": []BreadCrumb{
+			{
+				Title: "Audit Log",
+				Path:  r.URL.Path,
+			},
+		},
+		"Entries":     entries[start:end],
+		"TotalCount":  len(entries),
+		"CurrentPage": page,
+		"PageCount":   pageCount,
+		"PrevURL":     pageURL(page - 1),
+		"NextURL":     pageURL(page + 1),
+		"ExportURL":   exportURL,
+		"Filter": map[string]string{
+			"Actor":   query.Get("actor"),
+			"Action":  query.Get("action"),
+			"Target":  query.Get("target"),
+			"Outcome": query.Get("outcome"),
+			"Since":   query.Get("since"),
+			"Until":   query.Get("until"),
+		},
+		"Actions":   auditActions,
+		"MenuItems": GetMenuItems(h.basePath),
+	}
+
+	// Check if this is an HTMX request, if so render only the content
+	if r.Header.Get("HX-Request") == "true" {
+		if err := h.render(w, r, "AuditContent", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		if err := h.render(w, r, "Layout", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// AuditExport returns the audit entries matching the same filters as the
+// Audit page as a JSON array, as a file download when download=1
+func (h *Handlers) AuditExport(w http.ResponseWriter, r *http.Request) {
+	filter, err := auditFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.auditLog.Query(filter)
+	if err != nil {
+		log.Printf("❌ Failed to read audit log: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Query().Get("download") == "1" {
+		filename := fmt.Sprintf("zep-audit-%s.json", time.Now().UTC().Format("20060102-150405"))
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(entries)
+}
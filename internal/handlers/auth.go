@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/schizoidcock/zep-web-interface/internal/auth"
+)
+
+// loginFailureDelay slows down password guessing against the login form
+const loginFailureDelay = time.Second
+
+// LoginPage renders the shared-secret login form, or the single sign-on
+// button when OIDC is configured
+func (h *Handlers) LoginPage(w http.ResponseWriter, r *http.Request) {
+	if !h.auth.LoginEnabled() {
+		http.Redirect(w, r, h.basePath, http.StatusFound)
+		return
+	}
+
+	h.renderLogin(w, r, r.URL.Query().Get("next"), "")
+}
+
+// Login checks the shared secret and issues a signed session cookie
+func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
+	if !h.auth.LoginEnabled() || h.auth.OIDCEnabled() {
+		http.Redirect(w, r, h.basePath, http.StatusFound)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	next := r.FormValue("next")
+	identity, ok := h.auth.PasswordLogin(w, r.FormValue("password"))
+	entry := h.auditEntry(r, "auth.login", "account", "")
+	entry.Details = map[string]string{"method": "password"}
+	if !ok {
+		log.Printf("🔒 Failed login attempt from %s", r.RemoteAddr)
+		h.recordAudit(entry, errors.New("incorrect password"))
+		time.Sleep(loginFailureDelay)
+		w.WriteHeader(http.StatusUnauthorized)
+		h.renderLogin(w, r, next, "Incorrect password")
+		return
+	}
+
+	log.Printf("🔓 Login as %s (%s) from %s", identity.Username, identity.Role, r.RemoteAddr)
+	entry.Actor = auditActor(identity)
+	entry.TargetID = entry.Actor
+	entry.Role = string(identity.Role)
+	h.recordAudit(entry, nil)
+	http.Redirect(w, r, auth.SafeRedirect(next, h.basePath), http.StatusFound)
+}
+
+// OIDCLogin starts single sign-on by redirecting to the identity provider
+func (h *Handlers) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if !h.auth.OIDCEnabled() {
+		http.Redirect(w, r, h.auth.LoginPath(), http.StatusFound)
+		return
+	}
+
+	next := r.URL.Query().Get("next")
+	if err := h.auth.StartOIDCLogin(w, r, next); err != nil {
+		log.Printf("❌ Failed to start SSO login: %v", err)
+		w.WriteHeader(http.StatusBadGateway)
+		h.renderLogin(w, r, next, "Single sign-on is unavailable: "+err.Error())
+	}
+}
+
+// OIDCCallback completes single sign-on when the identity provider redirects back
+func (h *Handlers) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if !h.auth.OIDCEnabled() {
+		http.Redirect(w, r, h.auth.LoginPath(), http.StatusFound)
+		return
+	}
+
+	next, identity, err := h.auth.FinishOIDCLogin(w, r)
+	entry := h.auditEntry(r, "auth.login", "account", "")
+	entry.Details = map[string]string{"method": "oidc"}
+	if identity != nil {
+		entry.Actor = auditActor(identity)
+		entry.TargetID = entry.Actor
+		entry.Role = string(identity.Role)
+	}
+	if err != nil {
+		log.Printf("🔒 SSO login rejected from %s: %v", r.RemoteAddr, err)
+		h.recordAudit(entry, err)
+		status := http.StatusUnauthorized
+		if errors.Is(err, auth.ErrAccessDenied) {
+			status = http.StatusForbidden
+		}
+		w.WriteHeader(status)
+		h.renderLogin(w, r, next, err.Error())
+		return
+	}
+
+	log.Printf("🔓 SSO login from %s", r.RemoteAddr)
+	h.recordAudit(entry, nil)
+	http.Redirect(w, r, auth.SafeRedirect(next, h.basePath), http.StatusFound)
+}
+
+// CurrentIdentity renders the signed-in operator for the header, or JSON
+// for non-HTMX requests
+func (h *Handlers) CurrentIdentity(w http.ResponseWriter, r *http.Request) {
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Not signed in", http.StatusNotFound)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		data := map[string]interface{}{
+			"Identity": identity,
+		}
+		if err := h.render(w, r, "CurrentIdentity", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(identity)
+}
+
+// Logout clears the session cookie and returns to the login form
+func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
+	h.auth.SignOut(w)
+	http.Redirect(w, r, h.auth.LoginPath(), http.StatusFound)
+}
+
+func (h *Handlers) renderLogin(w http.ResponseWriter, r *http.Request, next, loginError string) {
+	data := map[string]interface{}{
+		"Title": "Sign in",
+		"Next":  next,
+		"Error": loginError,
+		"OIDC":  h.auth.OIDCEnabled(),
+	}
+
+	if err := h.render(w, r, "LoginPage", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Permissions tells templates which actions to offer the signed-in operator
+type Permissions struct {
+	Role      auth.Role
+	CanEdit   bool // create and update users
+	CanExport bool // export a user's full data
+	CanDelete bool // delete users and sessions
+}
+
+// permissions returns what the request's operator may do
+func (h *Handlers) permissions(r *http.Request) Permissions {
+	role := auth.RoleViewer
+	if identity, ok := auth.FromContext(r.Context()); ok {
+		role = identity.Role
+	}
+	return Permissions{
+		Role:      role,
+		CanEdit:   role.Allows(auth.RoleOperator),
+		CanExport: role.Allows(auth.RoleOperator),
+		CanDelete: role.Allows(auth.RoleAdmin),
+	}
+}
+
+// render executes a template with the CSRF token that pages embed in their
+// meta tag and forms, so page data does not have to carry it
+func (h *Handlers) render(w http.ResponseWriter, r *http.Request, name string, data interface{}) error {
+	switch data := data.(type) {
+	case map[string]interface{}:
+		data["CSRFToken"] = auth.CSRFToken(r)
+	case *PageData:
+		data.CSRFToken = auth.CSRFToken(r)
+	}
+	return h.templates.ExecuteTemplate(w, name, data)
+}
@@ -2,23 +2,38 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/schizoidcock/zep-web-interface/internal/audit"
 	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
 )
 
 // DeletionStatus tracks the status of user deletion operations
 type DeletionStatus struct {
 	UserID    string    `json:"user_id"`
-	Status    string    `json:"status"`    // "started", "sessions_done", "graph_cleanup", "completed", "failed"
+	Status    string    `json:"status"`    // "started", "deleting_sessions", "deleting_user", "completed", "failed"
 	Progress  int       `json:"progress"`  // 0-100
 	Message   string    `json:"message"`
 	StartedAt time.Time `json:"started_at"`
 	Error     string    `json:"error,omitempty"`
+	
+	// Session deletion counts, once the user's sessions are known
+	SessionsDeleted int `json:"sessions_deleted"`
+	SessionsTotal   int `json:"sessions_total"`
+	
+	// Sessions left behind by a completed deletion, which can be retried
+	Partial        bool                          `json:"partial"`
+	FailedSessions []zepapi.SessionDeletionError `json:"failed_sessions,omitempty"`
+	SessionsError  string                        `json:"sessions_error,omitempty"`
+	RetryURL       string                        `json:"retry_url,omitempty"`
 }
 
 // DeletionTracker manages deletion status tracking
@@ -31,18 +46,24 @@ var deletionTracker = &DeletionTracker{
 	statuses: make(map[string]*DeletionStatus),
 }
 
-// TrackDeletion starts tracking a deletion operation
-func (dt *DeletionTracker) TrackDeletion(userID string) {
+// TrackDeletion starts tracking a deletion operation. It returns false if a
+// deletion for the user is already running.
+func (dt *DeletionTracker) TrackDeletion(userID string) bool {
 	dt.mutex.Lock()
 	defer dt.mutex.Unlock()
 	
+	if existing, exists := dt.statuses[userID]; exists && existing.Status != "completed" && existing.Status != "failed" {
+		return false
+	}
+	
 	dt.statuses[userID] = &DeletionStatus{
 		UserID:    userID,
 		Status:    "started",
-		Progress:  10,
-		Message:   "Starting user deletion...",
+		Progress:  5,
+		Message:   "Fetching user sessions...",
 		StartedAt: time.Now(),
 	}
+	return true
 }
 
 // UpdateStatus updates the deletion progress
@@ -57,8 +78,34 @@ func (dt *DeletionTracker) UpdateStatus(userID, status, message string, progress
 	}
 }
 
-// MarkCompleted marks deletion as completed
-func (dt *DeletionTracker) MarkCompleted(userID string) {
+// UpdateSessions records session deletion counts. Sessions account for
+// 10-90% of the overall progress; the rest is fetching them and deleting
+// the user itself.
+func (dt *DeletionTracker) UpdateSessions(userID string, completed, total int) {
+	dt.mutex.Lock()
+	defer dt.mutex.Unlock()
+	
+	deletion, exists := dt.statuses[userID]
+	if !exists {
+		return
+	}
+	
+	deletion.SessionsDeleted = completed
+	deletion.SessionsTotal = total
+	if completed < total {
+		deletion.Status = "deleting_sessions"
+		deletion.Message = fmt.Sprintf("Deleted %d of %d sessions", completed, total)
+		deletion.Progress = 10 + completed*80/total
+	} else {
+		deletion.Status = "deleting_user"
+		deletion.Message = "Deleting user from server..."
+		deletion.Progress = 90
+	}
+}
+
+// MarkCompleted marks deletion as completed, recording any sessions the
+// cleanup left behind
+func (dt *DeletionTracker) MarkCompleted(userID string, result *zepapi.UserDeletionResult, retryURL string) {
 	dt.mutex.Lock()
 	defer dt.mutex.Unlock()
 	
@@ -66,6 +113,17 @@ func (dt *DeletionTracker) MarkCompleted(userID string) {
 		deletion.Status = "completed"
 		deletion.Message = "User deletion completed successfully"
 		deletion.Progress = 100
+		deletion.SessionsDeleted = result.SessionsDeleted
+		deletion.SessionsTotal = result.SessionsTotal
+		deletion.FailedSessions = result.FailedSessions
+		deletion.SessionsError = result.SessionsError
+		if result.Partial() {
+			deletion.Partial = true
+			deletion.Message = partialDeletionMessage(result)
+			if len(result.FailedSessions) > 0 {
+				deletion.RetryURL = retryURL
+			}
+		}
 		
 		// Auto-cleanup after 30 seconds
 		go func() {
@@ -98,13 +156,44 @@ func (dt *DeletionTracker) MarkFailed(userID, error string) {
 	}
 }
 
+// UpdateFailedSessions replaces the sessions left behind by a completed
+// deletion after a retry
+func (dt *DeletionTracker) UpdateFailedSessions(userID string, failed []zepapi.SessionDeletionError, deleted int) {
+	dt.mutex.Lock()
+	defer dt.mutex.Unlock()
+	
+	if deletion, exists := dt.statuses[userID]; exists && deletion.Status == "completed" {
+		deletion.FailedSessions = failed
+		deletion.SessionsDeleted += deleted
+		if len(failed) == 0 {
+			deletion.RetryURL = ""
+			deletion.Partial = deletion.SessionsError != ""
+		}
+	}
+}
+
+// partialDeletionMessage describes what a partial user deletion left behind
+func partialDeletionMessage(result *zepapi.UserDeletionResult) string {
+	if result.SessionsError != "" {
+		return "User deleted, but their sessions could not be listed and may remain"
+	}
+	return fmt.Sprintf("User deleted, but %d of %d sessions could not be deleted", len(result.FailedSessions), result.SessionsTotal)
+}
+
 // GetStatus retrieves deletion status
 func (dt *DeletionTracker) GetStatus(userID string) (*DeletionStatus, bool) {
 	dt.mutex.RLock()
 	defer dt.mutex.RUnlock()
 	
 	status, exists := dt.statuses[userID]
-	return status, exists
+	if !exists {
+		return nil, false
+	}
+	
+	// Return a copy so callers can encode it without holding the lock
+	snapshot := *status
+	snapshot.FailedSessions = append([]zepapi.SessionDeletionError(nil), status.FailedSessions...)
+	return &snapshot, true
 }
 
 // DeletionStatus endpoint to check deletion progress
@@ -126,14 +215,30 @@ func (h *Handlers) DeletionStatus(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(status)
 }
 
-// Enhanced DeleteUser with progress tracking
+// DeleteUserEnhanced starts a tracked background deletion and returns
+// immediately with a tracking URL for DeletionStatus, so no caller waits on
+// the deletion inside the request timeout
 func (h *Handlers) DeleteUserEnhanced(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userId")
+	trackingURL := fmt.Sprintf("%s/api/users/%s/deletion-status", h.basePath, userID)
 	
-	// Start tracking the deletion
-	deletionTracker.TrackDeletion(userID)
+	// Start tracking the deletion, unless one is already running
+	if !deletionTracker.TrackDeletion(userID) {
+		status, _ := deletionTracker.GetStatus(userID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":       status.Status,
+			"user_id":      userID,
+			"message":      "User deletion already in progress",
+			"progress":     status.Progress,
+			"tracking_url": trackingURL,
+		})
+		return
+	}
+	log.Printf("🗑️ Starting tracked user deletion for: %s", userID)
+	entry := h.auditEntry(r, "user.delete", "user", userID)
 	
-	// Start deletion in background with detailed progress tracking
+	// Start deletion in background with progress driven by session deletions
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -141,52 +246,435 @@ func (h *Handlers) DeleteUserEnhanced(w http.ResponseWriter, r *http.Request) {
 			}
 		}()
 		
-		// Step 1: Get sessions
-		deletionTracker.UpdateStatus(userID, "fetching_sessions", "Retrieving user sessions...", 20)
-		sessions, err := h.apiClient.GetUserSessions(userID)
-		if err != nil {
-			deletionTracker.UpdateStatus(userID, "sessions_partial", "Could not fetch all sessions, continuing...", 30)
-			sessions = []zepapi.Session{} // Continue anyway
-		} else {
-			deletionTracker.UpdateStatus(userID, "sessions_found", fmt.Sprintf("Found %d sessions to delete", len(sessions)), 40)
-		}
-		
-		// Step 2: Delete sessions concurrently
-		if len(sessions) > 0 {
-			deletionTracker.UpdateStatus(userID, "deleting_sessions", "Deleting user sessions...", 50)
-			// This will use the concurrent deletion we implemented
-		} else {
-			deletionTracker.UpdateStatus(userID, "no_sessions", "No sessions to delete", 60)
-		}
-		
-		// Step 3: Graph cleanup (background)
-		deletionTracker.UpdateStatus(userID, "graph_cleanup", "Starting graph data cleanup...", 70)
-		
-		// Step 4: Delete user from Zep server
-		deletionTracker.UpdateStatus(userID, "deleting_user", "Deleting user from server...", 80)
-		err = h.apiClient.DeleteUserWithCleanup(userID)
+		result, err := h.apiClient.DeleteUserWithProgress(userID, func(completed, total int) {
+			deletionTracker.UpdateSessions(userID, completed, total)
+		})
+		h.recordDeletionAudit(entry, result, err)
 		if err != nil {
+			log.Printf("❌ User deletion failed for %s: %v", userID, err)
 			deletionTracker.MarkFailed(userID, err.Error())
 			return
 		}
 		
-		// Step 5: Clear cache
-		deletionTracker.UpdateStatus(userID, "clearing_cache", "Clearing cached data...", 90)
+		// Clear cached data
 		h.cache.Delete(fmt.Sprintf("user:%s", userID))
 		h.cache.Delete(fmt.Sprintf("episodes:%s", userID))
 		h.cache.Delete(fmt.Sprintf("graph:%s", userID))
+		h.invalidateUserList()
 		
-		// Mark as completed
-		deletionTracker.MarkCompleted(userID)
+		log.Printf("✅ User deletion completed for: %s", userID)
+		deletionTracker.MarkCompleted(userID, result, h.retrySessionsURL(userID))
 	}()
 	
 	// Return deletion tracking info immediately
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", trackingURL)
+	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":        "started",
-		"user_id":       userID,
-		"message":       "User deletion started in background",
-		"progress":      10,
-		"tracking_url":  fmt.Sprintf("%s/api/users/%s/deletion-status", h.basePath, userID),
+		"status":       "started",
+		"user_id":      userID,
+		"message":      "User deletion started in background",
+		"progress":     5,
+		"tracking_url": trackingURL,
 	})
-}
\ No newline at end of file
+}
+
+// bulkDeleteConfirmation is the phrase users must type to confirm deleting n users
+func bulkDeleteConfirmation(n int) string {
+	if n == 1 {
+		return "delete 1 user"
+	}
+	return fmt.Sprintf("delete %d users", n)
+}
+
+// BulkDeleteUsers deletes the selected users as a background job. The form
+// carries one user_id value per user and a typed confirm phrase that must
+// match the number of users selected.
+func (h *Handlers) BulkDeleteUsers(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	
+	// Collect unique, non-empty user IDs
+	seen := make(map[string]bool)
+	var userIDs []string
+	for _, id := range r.Form["user_id"] {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		userIDs = append(userIDs, id)
+	}
+	if len(userIDs) == 0 {
+		http.Error(w, "No users selected", http.StatusBadRequest)
+		return
+	}
+	
+	expected := bulkDeleteConfirmation(len(userIDs))
+	if strings.TrimSpace(strings.ToLower(r.FormValue("confirm"))) != expected {
+		http.Error(w, fmt.Sprintf("Type %q to confirm", expected), http.StatusBadRequest)
+		return
+	}
+	
+	job := jobTracker.StartJob("bulk_user_delete", fmt.Sprintf("Delete %d users", len(userIDs)), BreadCrumb{
+		Title: "Users",
+		Path:  h.basePath + "/users",
+	}, len(userIDs))
+	log.Printf("🗑️ Starting bulk deletion job %s for %d users", job.ID, len(userIDs))
+	
+	// Each user gets its own audit entry, tied to the job
+	entry := h.auditEntry(r, "user.delete", "user", "")
+	entry.Details = map[string]string{"job_id": job.ID}
+	
+	go func() {
+		err := h.apiClient.BulkDeleteUsers(userIDs, func(completed, total int, userID string, err error) {
+			result := JobResult{ID: userID, Status: "succeeded"}
+			var partial *zepapi.PartialDeletionError
+			if errors.As(err, &partial) {
+				result.Status = "partial"
+				result.Error = err.Error()
+			} else if err != nil {
+				result.Status = "failed"
+				result.Error = err.Error()
+			}
+			userEntry := entry
+			userEntry.TargetID = userID
+			if result.Status == "partial" {
+				userEntry.Outcome = audit.OutcomePartial
+			}
+			h.recordAudit(userEntry, err)
+			if result.Status != "failed" {
+				h.cache.Delete(fmt.Sprintf("user:%s", userID))
+				h.cache.Delete(fmt.Sprintf("episodes:%s", userID))
+				h.cache.Delete(fmt.Sprintf("graph:%s", userID))
+				h.invalidateUserList()
+			}
+			jobTracker.AddResult(job.ID, result)
+		})
+		jobTracker.Finish(job.ID, err)
+	}()
+	
+	jobURL := h.basePath + "/jobs/" + job.ID
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  "started",
+			"job_id":  job.ID,
+			"job_url": jobURL,
+			"total":   len(userIDs),
+		})
+		return
+	}
+	http.Redirect(w, r, jobURL, http.StatusSeeOther)
+}
+
+// sessionSelection is the set of sessions a bulk session deletion applies to:
+// either explicitly selected session IDs or every session matching a filter
+type sessionSelection struct {
+	SessionIDs    []string
+	Selected      bool // true when SessionIDs came from the session_id form values
+	UserID        string
+	CreatedBefore string
+	Ended         string
+	Filter        zepapi.SessionFilter
+}
+
+// parseSessionSelection reads session_id values, or the user_id,
+// created_before (YYYY-MM-DD) and ended (true/false) filter, from the
+// request. A filter is only parsed here; finding its sessions walks every
+// session page, so callers decide how far to go.
+func parseSessionSelection(r *http.Request) (*sessionSelection, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("failed to parse form")
+	}
+	
+	selection := &sessionSelection{
+		UserID:        strings.TrimSpace(r.FormValue("user_id")),
+		CreatedBefore: strings.TrimSpace(r.FormValue("created_before")),
+		Ended:         r.FormValue("ended"),
+	}
+	
+	// Explicitly selected sessions take precedence over the filter
+	seen := make(map[string]bool)
+	for _, id := range r.Form["session_id"] {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		selection.SessionIDs = append(selection.SessionIDs, id)
+	}
+	if len(selection.SessionIDs) > 0 {
+		selection.Selected = true
+		return selection, nil
+	}
+	
+	selection.Filter.UserID = selection.UserID
+	if selection.CreatedBefore != "" {
+		createdBefore, err := time.Parse("2006-01-02", selection.CreatedBefore)
+		if err != nil {
+			return nil, fmt.Errorf("invalid created_before date %q", selection.CreatedBefore)
+		}
+		selection.Filter.CreatedBefore = createdBefore
+	}
+	switch selection.Ended {
+	case "true", "false":
+		ended := selection.Ended == "true"
+		selection.Filter.Ended = &ended
+	case "":
+	default:
+		return nil, fmt.Errorf("invalid ended value %q", selection.Ended)
+	}
+	if selection.Filter.IsEmpty() {
+		return nil, fmt.Errorf("select sessions or choose at least one filter")
+	}
+	return selection, nil
+}
+
+// sessionDeleteConfirmation is the phrase users must type to confirm deleting n sessions
+func sessionDeleteConfirmation(n int) string {
+	if n == 1 {
+		return "delete 1 session"
+	}
+	return fmt.Sprintf("delete %d sessions", n)
+}
+
+// allMatchingSessionsConfirmation is the phrase for a filter whose preview
+// stopped before counting every match
+const allMatchingSessionsConfirmation = "delete all matching sessions"
+
+// sessionPreviewScanLimit is how many sessions a filter preview reads
+// before it stops counting, keeping the preview well inside the request
+// timeout on large servers
+const sessionPreviewScanLimit = 1000
+
+// BulkDeleteSessionsPreview renders the sessions a bulk deletion would remove
+// along with the typed confirmation form. A filter is counted over the first
+// sessionPreviewScanLimit sessions only.
+func (h *Handlers) BulkDeleteSessionsPreview(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{}
+	
+	selection, err := parseSessionSelection(r)
+	complete := true
+	if err == nil && !selection.Selected {
+		var sessions []zepapi.Session
+		sessions, complete, err = h.apiClient.ScanSessions(selection.Filter, sessionPreviewScanLimit)
+		if err != nil {
+			err = fmt.Errorf("failed to find sessions: %w", err)
+		}
+		for _, s := range sessions {
+			selection.SessionIDs = append(selection.SessionIDs, s.SessionID)
+		}
+	}
+	if err != nil {
+		data["Error"] = err.Error()
+	} else {
+		count := len(selection.SessionIDs)
+		sample := selection.SessionIDs
+		if len(sample) > 10 {
+			sample = sample[:10]
+		}
+		data["Selection"] = selection
+		data["Count"] = count
+		data["Sample"] = sample
+		data["Complete"] = complete
+		data["ScanLimit"] = sessionPreviewScanLimit
+		data["Expected"] = strconv.Itoa(count)
+		data["Phrase"] = sessionDeleteConfirmation(count)
+		if !complete {
+			data["Expected"] = "all"
+			data["Phrase"] = allMatchingSessionsConfirmation
+		}
+	}
+	
+	if err := h.render(w, r, "SessionBulkDeletePreview", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// BulkDeleteSessions deletes the selected or filtered sessions as a
+// background job. Selected sessions must match the number in the confirm
+// phrase. A filter is resolved inside the job, which fails without deleting
+// anything when the number of matches no longer equals the expected count
+// the preview confirmed, unless the preview confirmed all matching sessions.
+func (h *Handlers) BulkDeleteSessions(w http.ResponseWriter, r *http.Request) {
+	selection, err := parseSessionSelection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	expectedCount := len(selection.SessionIDs)
+	expected := sessionDeleteConfirmation(expectedCount)
+	if !selection.Selected {
+		switch value := r.FormValue("expected"); value {
+		case "all":
+			expectedCount = -1
+			expected = allMatchingSessionsConfirmation
+		default:
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				http.Error(w, "No sessions match", http.StatusBadRequest)
+				return
+			}
+			expectedCount = n
+			expected = sessionDeleteConfirmation(n)
+		}
+	}
+	if strings.TrimSpace(strings.ToLower(r.FormValue("confirm"))) != expected {
+		http.Error(w, fmt.Sprintf("Type %q to confirm", expected), http.StatusBadRequest)
+		return
+	}
+	
+	title := fmt.Sprintf("Delete %d sessions", len(selection.SessionIDs))
+	if !selection.Selected {
+		title = "Delete sessions matching filter"
+	}
+	job := jobTracker.StartJob("bulk_session_delete", title, BreadCrumb{
+		Title: "Sessions",
+		Path:  h.basePath + "/sessions",
+	}, len(selection.SessionIDs))
+	log.Printf("🗑️ Starting bulk session deletion job %s", job.ID)
+	
+	entry := h.auditEntry(r, "session.delete", "session", "")
+	entry.Details = map[string]string{"job_id": job.ID}
+	
+	go func() {
+		sessionIDs := selection.SessionIDs
+		if !selection.Selected {
+			sessions, err := h.apiClient.FindSessions(selection.Filter)
+			if err != nil {
+				jobTracker.Finish(job.ID, fmt.Errorf("failed to find sessions: %w", err))
+				return
+			}
+			if expectedCount >= 0 && len(sessions) != expectedCount {
+				jobTracker.Finish(job.ID, fmt.Errorf("%d sessions now match the filter, not the %d confirmed; preview the deletion again", len(sessions), expectedCount))
+				return
+			}
+			for _, s := range sessions {
+				sessionIDs = append(sessionIDs, s.SessionID)
+			}
+			jobTracker.SetTotal(job.ID, len(sessionIDs))
+		}
+		log.Printf("🗑️ Bulk session deletion job %s deleting %d sessions", job.ID, len(sessionIDs))
+		
+		err := h.apiClient.BulkDeleteSessions(sessionIDs, func(completed, total int, sessionID string, err error) {
+			sessionEntry := entry
+			sessionEntry.TargetID = sessionID
+			h.recordAudit(sessionEntry, err)
+			result := JobResult{ID: sessionID, Status: "succeeded"}
+			if err != nil {
+				result.Status = "failed"
+				result.Error = err.Error()
+			}
+			jobTracker.AddResult(job.ID, result)
+		})
+		
+		// Session counts in the user list are now stale
+		h.invalidateUserList()
+		jobTracker.Finish(job.ID, err)
+	}()
+	
+	jobURL := h.basePath + "/jobs/" + job.ID
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  "started",
+			"job_id":  job.ID,
+			"job_url": jobURL,
+			"total":   len(selection.SessionIDs),
+		})
+		return
+	}
+	http.Redirect(w, r, jobURL, http.StatusSeeOther)
+}
+
+// retrySessionsURL is where the sessions a user deletion left behind can be retried
+func (h *Handlers) retrySessionsURL(userID string) string {
+	return fmt.Sprintf("%s/users/%s/sessions/retry-delete", h.basePath, userID)
+}
+
+// RetrySessionDeletion retries deleting sessions that a user deletion left
+// behind. The form carries one session_id value per session, each of which
+// must be one of the user's failed sessions; the response lists the sessions
+// that still could not be deleted.
+func (h *Handlers) RetrySessionDeletion(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userId")
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	
+	sessionIDs := r.Form["session_id"]
+	if len(sessionIDs) == 0 {
+		http.Error(w, "No sessions to retry", http.StatusBadRequest)
+		return
+	}
+	
+	if rejected := h.rejectedRetries(userID, sessionIDs); len(rejected) > 0 {
+		http.Error(w, fmt.Sprintf("Sessions not left behind by user %s: %s", userID, strings.Join(rejected, ", ")), http.StatusBadRequest)
+		return
+	}
+	
+	log.Printf("🔁 Retrying deletion of %d session(s) left behind by user %s", len(sessionIDs), userID)
+	failed := []zepapi.SessionDeletionError{}
+	var failedMu sync.Mutex // the callback runs on BulkDeleteSessions' workers
+	entry := h.auditEntry(r, "session.delete", "session", "")
+	entry.Details = map[string]string{"retry_for_user": userID}
+	h.apiClient.BulkDeleteSessions(sessionIDs, func(completed, total int, sessionID string, err error) {
+		sessionEntry := entry
+		sessionEntry.TargetID = sessionID
+		h.recordAudit(sessionEntry, err)
+		if err != nil {
+			failedMu.Lock()
+			failed = append(failed, zepapi.SessionDeletionError{SessionID: sessionID, Error: err.Error()})
+			failedMu.Unlock()
+		}
+	})
+	deleted := len(sessionIDs) - len(failed)
+	deletionTracker.UpdateFailedSessions(userID, failed, deleted)
+	
+	w.Header().Set("Content-Type", "application/json")
+	if len(failed) > 0 {
+		w.WriteHeader(http.StatusMultiStatus)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id":          userID,
+		"sessions_deleted": deleted,
+		"failed_sessions":  failed,
+		"partial":          len(failed) > 0,
+	})
+}
+
+// rejectedRetries returns the sessions a retry for a user may not delete.
+// They are checked against the failed sessions of the user's tracked
+// deletion or, once that status has been cleaned up, against the user Zep
+// records for each session.
+func (h *Handlers) rejectedRetries(userID string, sessionIDs []string) []string {
+	status, tracked := deletionTracker.GetStatus(userID)
+	failed := map[string]bool{}
+	if tracked {
+		for _, session := range status.FailedSessions {
+			failed[session.SessionID] = true
+		}
+	}
+	
+	var rejected []string
+	for _, sessionID := range sessionIDs {
+		if tracked {
+			if !failed[sessionID] {
+				rejected = append(rejected, sessionID)
+			}
+			continue
+		}
+		session, err := h.apiClient.GetSession(sessionID)
+		if err != nil || session.UserID != userID {
+			rejected = append(rejected, sessionID)
+		}
+	}
+	return rejected
+}
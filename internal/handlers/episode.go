@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+// EpisodeNode is a node extracted from an episode
+type EpisodeNode struct {
+	*zepapi.EntityNode
+	URL        string
+	Attributes []NodeAttribute
+}
+
+// EpisodeEdge is an edge extracted from an episode, with the names of the
+// nodes it joins
+type EpisodeEdge struct {
+	*zepapi.EntityEdge
+	Source    string
+	Target    string
+	SourceURL string
+	TargetURL string
+	ValidFrom string // formatted; empty when open
+	ValidTo   string
+	ExpiredAt string
+}
+
+// UserEpisode shows one of a user's episodes next to the nodes and edges
+// Graphiti extracted from it
+func (h *Handlers) UserEpisode(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userId")
+	episodeID := chi.URLParam(r, "episodeId")
+
+	// The episode list tells us the episode belongs to this user
+	episodes, err := h.loadUserEpisodes(userID)
+	if err != nil {
+		log.Printf("❌ Failed to load episodes of user %s: %v", userID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var episode *zepapi.Episode
+	for i := range episodes {
+		if episodes[i].EpisodeID == episodeID {
+			episode = &episodes[i]
+			break
+		}
+	}
+	if episode == nil {
+		http.Error(w, "Episode not found for this user", http.StatusNotFound)
+		return
+	}
+
+	nodesPath := h.basePath + "/users/" + userID + "/graph/nodes/"
+	var nodes []EpisodeNode
+	var edges []EpisodeEdge
+	mentions, mentionsErr := h.apiClient.GetEpisodeMentionsContext(r.Context(), episodeID)
+	if mentionsErr != nil {
+		log.Printf("❌ Failed to get mentions of episode %s: %v", episodeID, mentionsErr)
+	} else {
+		names := make(map[string]string, len(mentions.Nodes))
+		for _, node := range mentions.Nodes {
+			names[node.UUID] = node.Name
+			nodes = append(nodes, EpisodeNode{
+				EntityNode: node,
+				URL:        nodesPath + node.UUID,
+				Attributes: nodeAttributes(node.Attributes),
+			})
+		}
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+
+		for _, edge := range mentions.Edges {
+			edges = append(edges, EpisodeEdge{
+				EntityEdge: edge,
+				Source:     nodeName(names, edge.SourceNodeUUID),
+				Target:     nodeName(names, edge.TargetNodeUUID),
+				SourceURL:  nodesPath + edge.SourceNodeUUID,
+				TargetURL:  nodesPath + edge.TargetNodeUUID,
+				ValidFrom:  formatOptionalGraphTime(edge.ValidAt),
+				ValidTo:    formatOptionalGraphTime(edge.InvalidAt),
+				ExpiredAt:  formatOptionalGraphTime(edge.ExpiredAt),
+			})
+		}
+	}
+
+	episodesPath := h.basePath + "/users/" + userID + "/episodes"
+	data := map[string]interface{}{
+		"Title":    "Episode",
+		"SubTitle": "What the graph extracted from episode " + episodeID,
+		"Page":     "user_episode",
+		"Path":     r.URL.Path,
+		"BreadCrumbs": []BreadCrumb{
+			{
+				Title: "Users",
+				Path:  h.basePath + "/users",
+			},
+			{
+				Title: "User Details",
+				Path:  h.basePath + "/users/" + userID,
+			},
+			{
+				Title: "Episodes",
+				Path:  episodesPath,
+			},
+			{
+				Title: episodeID,
+				Path:  r.URL.Path,
+			},
+		},
+		"UserID":    userID,
+		"Episode":   episode,
+		"Nodes":     nodes,
+		"Edges":     edges,
+		"ListURL":   episodesPath + "#episode-" + episodeID,
+		"MenuItems": GetMenuItems(h.basePath),
+	}
+	if episode.SessionID != "" {
+		data["SessionURL"] = h.basePath + "/sessions/" + episode.SessionID
+	}
+	if mentionsErr != nil {
+		data["MentionsError"] = mentionsErr.Error()
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		if err := h.render(w, r, "UserEpisodeContent", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		if err := h.render(w, r, "Layout", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// nodeName is the name of an extracted node, or its UUID when the episode's
+// mentions left it out
+func nodeName(names map[string]string, uuid string) string {
+	if name := names[uuid]; name != "" {
+		return name
+	}
+	return uuid
+}
+
+func formatOptionalGraphTime(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return formatGraphTime(*value)
+}
@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+const (
+	// exportPageSize is how many messages an export asks the Zep API for at a time
+	exportPageSize = 100
+	// maxExportPages stops an export that never runs out of pages
+	maxExportPages = 10000
+)
+
+// exportFormat describes one download format for a conversation export
+type exportFormat struct {
+	Extension   string
+	ContentType string
+	newWriter   func(w io.Writer) conversationWriter
+}
+
+var exportFormats = map[string]exportFormat{
+	"json":     {Extension: "json", ContentType: "application/json", newWriter: newJSONConversationWriter},
+	"jsonl":    {Extension: "jsonl", ContentType: "application/x-ndjson", newWriter: newJSONLConversationWriter},
+	"markdown": {Extension: "md", ContentType: "text/markdown; charset=utf-8", newWriter: newMarkdownConversationWriter},
+	"csv":      {Extension: "csv", ContentType: "text/csv; charset=utf-8", newWriter: newCSVConversationWriter},
+}
+
+// sessionExportLinks lists the formats in the order the session page offers them
+var sessionExportLinks = []struct {
+	Format string
+	Label  string
+}{
+	{Format: "json", Label: "JSON"},
+	{Format: "jsonl", Label: "JSONL"},
+	{Format: "markdown", Label: "Markdown"},
+	{Format: "csv", Label: "CSV"},
+}
+
+// conversationWriter writes a session's messages in one export format as
+// they arrive. End is only called once every message was written, so a
+// failed export is left visibly incomplete.
+type conversationWriter interface {
+	Begin(session *zepapi.Session) error
+	Write(message zepapi.Message) error
+	End() error
+}
+
+// exportMessage is the shape every format exports a message in
+type exportMessage struct {
+	UUID       string                 `json:"uuid,omitempty"`
+	Role       string                 `json:"role"`
+	Content    string                 `json:"content"`
+	CreatedAt  string                 `json:"created_at,omitempty"`
+	UpdatedAt  string                 `json:"updated_at,omitempty"`
+	TokenCount int                    `json:"token_count"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+}
+
+func newExportMessage(m zepapi.Message) exportMessage {
+	return exportMessage{
+		UUID:       m.UUID,
+		Role:       m.Role,
+		Content:    m.Content,
+		CreatedAt:  exportTime(m.CreatedAt),
+		UpdatedAt:  exportTime(m.UpdatedAt),
+		TokenCount: m.TokenCount,
+		Metadata:   m.Metadata,
+	}
+}
+
+func exportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// walkMessages calls fn with each page of a session's messages, oldest page
+// first, until the Zep API runs out of messages or ctx is done. A server that
+// ignores the page number would return the same page forever, so a repeated
+// page ends the walk, as does reaching maxExportPages.
+func (h *Handlers) walkMessages(ctx context.Context, sessionID string, fn func([]zepapi.Message) error) error {
+	seen := 0
+	var previous []zepapi.Message
+	for page := 1; ; page++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if page > maxExportPages {
+			return fmt.Errorf("stopped after %d pages of messages", maxExportPages)
+		}
+		messages, total, err := h.apiClient.GetMessageListContext(ctx, sessionID, page, exportPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to load messages page %d: %w", page, err)
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+		if samePage(previous, messages) {
+			// Everything came back at once if the page was larger than asked
+			if len(messages) > exportPageSize {
+				return nil
+			}
+			return fmt.Errorf("messages page %d repeats page %d, the server does not seem to page messages", page, page-1)
+		}
+		previous = messages
+		if err := fn(messages); err != nil {
+			return err
+		}
+		seen += len(messages)
+		if len(messages) < exportPageSize || (total > 0 && seen >= total) {
+			return nil
+		}
+	}
+}
+
+// samePage reports whether two pages hold the same messages
+func samePage(a, b []zepapi.Message) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].UUID != b[i].UUID || a[i].Content != b[i].Content || !a[i].CreatedAt.Equal(b[i].CreatedAt) {
+			return false
+		}
+	}
+	return true
+}
+
+// ExportSession streams a session's complete message history as a download
+func (h *Handlers) ExportSession(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionId")
+
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "json"
+	}
+	format, ok := exportFormats[name]
+	if !ok {
+		http.Error(w, "format must be json, jsonl, markdown or csv", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.apiClient.GetSession(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+exportFilename("session-"+sessionID, format.Extension)+`"`)
+
+	// Send each page on as soon as it is written rather than buffering the
+	// whole conversation
+	buffered := bufio.NewWriter(w)
+	flusher, _ := w.(http.Flusher)
+	out := format.newWriter(buffered)
+
+	count := 0
+	err = out.Begin(session)
+	if err == nil {
+		err = h.walkMessages(r.Context(), sessionID, func(messages []zepapi.Message) error {
+			for _, message := range messages {
+				if err := out.Write(message); err != nil {
+					return err
+				}
+			}
+			count += len(messages)
+			if err := buffered.Flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = out.End()
+	}
+	if flushErr := buffered.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		log.Printf("❌ Export of session %s stopped after %d messages: %v", sessionID, count, err)
+		return
+	}
+	log.Printf("✅ Exported %d messages from session %s as %s", count, sessionID, name)
+}
+
+// exportFilename keeps IDs safe to use in a Content-Disposition header
+func exportFilename(base, extension string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, base)
+	return safe + "." + extension
+}
+
+// jsonConversationWriter writes {"session": ..., "messages": [...]}
+type jsonConversationWriter struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONConversationWriter(w io.Writer) conversationWriter {
+	return &jsonConversationWriter{w: w}
+}
+
+func (c *jsonConversationWriter) Begin(session *zepapi.Session) error {
+	encoded, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.w, "{\"session\":%s,\"messages\":[", encoded)
+	return err
+}
+
+func (c *jsonConversationWriter) Write(message zepapi.Message) error {
+	encoded, err := json.Marshal(newExportMessage(message))
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if c.count == 0 {
+		separator = "\n"
+	}
+	c.count++
+	_, err = fmt.Fprintf(c.w, "%s%s", separator, encoded)
+	return err
+}
+
+func (c *jsonConversationWriter) End() error {
+	_, err := io.WriteString(c.w, "\n]}\n")
+	return err
+}
+
+// jsonlConversationWriter writes one message object per line
+type jsonlConversationWriter struct {
+	encoder *json.Encoder
+}
+
+func newJSONLConversationWriter(w io.Writer) conversationWriter {
+	return &jsonlConversationWriter{encoder: json.NewEncoder(w)}
+}
+
+func (c *jsonlConversationWriter) Begin(*zepapi.Session) error { return nil }
+
+func (c *jsonlConversationWriter) Write(message zepapi.Message) error {
+	return c.encoder.Encode(newExportMessage(message))
+}
+
+func (c *jsonlConversationWriter) End() error { return nil }
+
+// markdownConversationWriter writes a readable transcript
+type markdownConversationWriter struct {
+	w io.Writer
+}
+
+func newMarkdownConversationWriter(w io.Writer) conversationWriter {
+	return &markdownConversationWriter{w: w}
+}
+
+func (c *markdownConversationWriter) Begin(session *zepapi.Session) error {
+	_, err := fmt.Fprintf(c.w, "# Session %s\n\n- User: %s\n- Created: %s\n",
+		session.SessionID, valueOrDash(session.UserID), valueOrDash(exportTime(session.CreatedAt)))
+	return err
+}
+
+func (c *markdownConversationWriter) Write(message zepapi.Message) error {
+	m := newExportMessage(message)
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n## %s", valueOrDash(m.Role))
+	if m.CreatedAt != "" {
+		fmt.Fprintf(&b, " · %s", m.CreatedAt)
+	}
+	fmt.Fprintf(&b, "\n\n%s\n\n_Tokens: %d_\n", m.Content, m.TokenCount)
+	if len(m.Metadata) > 0 {
+		encoded, err := json.MarshalIndent(m.Metadata, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "\n```json\n%s\n```\n", encoded)
+	}
+	_, err := io.WriteString(c.w, b.String())
+	return err
+}
+
+func (c *markdownConversationWriter) End() error { return nil }
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// csvConversationWriter writes one row per message, with metadata as JSON
+type csvConversationWriter struct {
+	w *csv.Writer
+}
+
+func newCSVConversationWriter(w io.Writer) conversationWriter {
+	return &csvConversationWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvConversationWriter) Begin(*zepapi.Session) error {
+	return c.w.Write([]string{"uuid", "role", "content", "created_at", "updated_at", "token_count", "metadata"})
+}
+
+func (c *csvConversationWriter) Write(message zepapi.Message) error {
+	m := newExportMessage(message)
+	metadata := ""
+	if len(m.Metadata) > 0 {
+		encoded, err := json.Marshal(m.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = string(encoded)
+	}
+	if err := c.w.Write([]string{m.UUID, m.Role, m.Content, m.CreatedAt, m.UpdatedAt, strconv.Itoa(m.TokenCount), metadata}); err != nil {
+		return err
+	}
+	// Hand the row to the response writer so page flushes include it
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvConversationWriter) End() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+// messageServer serves a session of count messages the way a Zep server
+// described by the flags would page them
+type messageServer struct {
+	count         int
+	ignorePage    bool // every page is the first page
+	ignoreSize    bool // every page holds all messages
+	reportNoTotal bool
+}
+
+func (s messageServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	size, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if s.ignorePage {
+		page = 1
+	}
+	if s.ignoreSize {
+		size = s.count
+	}
+	var messages []zepapi.Message
+	for i := (page - 1) * size; i < page*size && i < s.count; i++ {
+		messages = append(messages, zepapi.Message{UUID: fmt.Sprintf("m%d", i), Role: "user", Content: fmt.Sprintf("message %d", i)})
+	}
+	total := s.count
+	if s.reportNoTotal {
+		total = 0
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"messages": messages, "total_count": total})
+}
+
+func TestWalkMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  messageServer
+		want    int    // messages passed to fn
+		wantErr string // empty when the walk must succeed
+	}{
+		{name: "paged", server: messageServer{count: 250}, want: 250},
+		{name: "paged without total", server: messageServer{count: 200, reportNoTotal: true}, want: 200},
+		{name: "empty session", server: messageServer{count: 0}, want: 0},
+		{name: "one short page", server: messageServer{count: 7}, want: 7},
+		{name: "page size ignored", server: messageServer{count: 150, ignoreSize: true, reportNoTotal: true}, want: 150},
+		{name: "page number ignored", server: messageServer{count: 250, ignorePage: true, reportNoTotal: true}, want: exportPageSize, wantErr: "repeats page 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.server)
+			defer server.Close()
+			h := &Handlers{apiClient: zepapi.NewClient(server.URL, "test-key", "")}
+
+			got := 0
+			seen := make(map[string]bool)
+			err := h.walkMessages(context.Background(), "s1", func(messages []zepapi.Message) error {
+				for _, message := range messages {
+					if seen[message.UUID] {
+						t.Errorf("message %s passed twice", message.UUID)
+					}
+					seen[message.UUID] = true
+				}
+				got += len(messages)
+				return nil
+			})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("walkMessages: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("walked %d messages, want %d", got, tt.want)
+			}
+		})
+	}
+}
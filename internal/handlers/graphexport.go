@@ -0,0 +1,574 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+// graphExportFormat describes one download format for a graph export. The
+// handler buffers w and reports any write error when it flushes.
+type graphExportFormat struct {
+	Extension   string
+	ContentType string
+	write       func(w io.Writer, graph *exportGraph) error
+}
+
+var graphExportFormats = map[string]graphExportFormat{
+	"graphml": {Extension: "graphml", ContentType: "application/graphml+xml", write: writeGraphML},
+	"gexf":    {Extension: "gexf", ContentType: "application/gexf+xml", write: writeGEXF},
+	"cypher":  {Extension: "cypher", ContentType: "text/plain; charset=utf-8", write: writeCypher},
+	"csv":     {Extension: "zip", ContentType: "application/zip", write: writeGraphCSV},
+}
+
+// graphExportLinks lists the formats in the order the graph page offers them
+var graphExportLinks = []struct {
+	Format string
+	Label  string
+}{
+	{Format: "graphml", Label: "GraphML"},
+	{Format: "gexf", Label: "GEXF"},
+	{Format: "cypher", Label: "Cypher"},
+	{Format: "csv", Label: "CSV"},
+}
+
+// exportGraph is a user's graph with what the formats need to describe it
+type exportGraph struct {
+	*zepapi.Graph
+	UserID     string
+	ExportedAt time.Time
+	// NodeAttributes are the node attribute names found in the graph,
+	// sorted, with the type every value of each fits
+	NodeAttributes []graphAttribute
+}
+
+// graphAttribute is a node attribute column. Type is a GraphML and GEXF
+// type name: "boolean", "double" or "string".
+type graphAttribute struct {
+	Name string
+	Type string
+}
+
+func newExportGraph(userID string, g *zepapi.Graph) *exportGraph {
+	graph := &exportGraph{Graph: g, UserID: userID, ExportedAt: time.Now().UTC()}
+
+	types := make(map[string]string)
+	for _, node := range graph.Nodes {
+		for name, value := range node.Attributes {
+			valueType := attributeType(value)
+			if previous, ok := types[name]; ok && previous != valueType {
+				valueType = "string"
+			}
+			types[name] = valueType
+		}
+	}
+	for name, valueType := range types {
+		graph.NodeAttributes = append(graph.NodeAttributes, graphAttribute{Name: name, Type: valueType})
+	}
+	sort.Slice(graph.NodeAttributes, func(i, j int) bool {
+		return graph.NodeAttributes[i].Name < graph.NodeAttributes[j].Name
+	})
+	return graph
+}
+
+// partialNote says which episodes a partial graph is missing, or is empty
+// for a complete graph. Every format carries it so a file cannot pass for
+// the whole graph.
+func (g *exportGraph) partialNote() string {
+	if !g.Partial() {
+		return ""
+	}
+	return fmt.Sprintf("Partial graph: the mentions of %d episode(s) could not be loaded: %s", len(g.FailedEpisodes), strings.Join(g.FailedEpisodes, ", "))
+}
+
+func attributeType(value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return "boolean"
+	case float64:
+		return "double"
+	default:
+		return "string"
+	}
+}
+
+// attributeText renders an attribute value for formats that only hold text.
+// Lists and objects are written as JSON.
+func attributeText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		encoded, err := attributeJSON(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return encoded
+	}
+}
+
+// attributeJSON encodes attributes without escaping <, > and &, which are
+// only a concern for JSON embedded in HTML
+func attributeJSON(value interface{}) (string, error) {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// loadUserGraph returns the user's graph from the cache. When the graph has
+// not been viewed recently it starts loading it in the background, shared
+// with the graph page, and returns the load's progress instead.
+func (h *Handlers) loadUserGraph(userID string) (*zepapi.Graph, *asyncProgress, error) {
+	if cached, found := h.cache.Get(fmt.Sprintf("graph:%s", userID)); found && cached != nil {
+		if graph, ok := cached.(*zepapi.Graph); ok {
+			return graph, nil, nil
+		}
+	}
+	// Report a recent failure instead of hammering the API again
+	if cached, found := h.cache.Get(fmt.Sprintf("graph:error:%s", userID)); found {
+		if failed, ok := cached.(AsyncData); ok {
+			return nil, nil, errors.New(failed.Error)
+		}
+	}
+	return nil, h.startGraphLoad(userID), nil
+}
+
+// graphStillLoading tells a script to come back once the graph is loaded.
+// Browsers get the graph loading page instead.
+func graphStillLoading(w http.ResponseWriter, progress *asyncProgress) {
+	status := progress.snapshot()
+	w.Header().Set("Retry-After", "5")
+	http.Error(w, fmt.Sprintf("The graph is still loading (%s), try again shortly", status.Message), http.StatusServiceUnavailable)
+}
+
+// ExportUserGraph downloads a user's graph as GraphML, GEXF, a Cypher CREATE
+// script, or a zip holding a node CSV and an edge CSV
+func (h *Handlers) ExportUserGraph(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userId")
+
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "graphml"
+	}
+	format, ok := graphExportFormats[name]
+	if !ok {
+		http.Error(w, "format must be graphml, gexf, cypher or csv", http.StatusBadRequest)
+		return
+	}
+
+	loaded, loading, err := h.loadUserGraph(userID)
+	if err != nil {
+		log.Printf("❌ Failed to load graph of user %s for export: %v", userID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if loading != nil {
+		if strings.Contains(r.Header.Get("Accept"), "text/html") {
+			h.userGraphLoading(w, r, userID, "Graph Export", r.URL.RequestURI(), loading)
+			return
+		}
+		graphStillLoading(w, loading)
+		return
+	}
+	graph := newExportGraph(userID, loaded)
+
+	base := "graph-" + userID
+	if name == "csv" {
+		base += "-csv"
+	}
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+exportFilename(base, format.Extension)+`"`)
+
+	buffered := bufio.NewWriter(w)
+	err = format.write(buffered, graph)
+	if flushErr := buffered.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		log.Printf("❌ Export of graph for user %s as %s failed: %v", userID, name, err)
+		return
+	}
+	log.Printf("✅ Exported graph of user %s as %s (%d nodes, %d edges, %d failed episodes)", userID, name, len(graph.Nodes), len(graph.Edges), len(graph.FailedEpisodes))
+}
+
+// xmlText escapes s for use in XML text and attribute values, replacing
+// characters XML cannot hold
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// graphLabels joins node labels the way Neo4j's GraphML import reads them
+func graphLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	return ":" + strings.Join(labels, ":")
+}
+
+// writeGraphML writes GraphML that Gephi, yEd, NetworkX and Neo4j's
+// apoc.import.graphml can read. Node attributes get a key each, prefixed
+// so they cannot clash with the fixed keys.
+func writeGraphML(w io.Writer, graph *exportGraph) error {
+	io.WriteString(w, xml.Header)
+	fmt.Fprintf(w, "<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\">\n")
+
+	keys := []struct{ id, scope, name string }{
+		{"label", "node", "label"},
+		{"labels", "node", "labels"},
+		{"summary", "node", "summary"},
+		{"created_at", "node", "created_at"},
+		{"updated_at", "node", "updated_at"},
+		{"e_label", "edge", "label"},
+		{"fact", "edge", "fact"},
+		{"e_created_at", "edge", "created_at"},
+		{"e_updated_at", "edge", "updated_at"},
+		{"valid_at", "edge", "valid_at"},
+		{"invalid_at", "edge", "invalid_at"},
+		{"expired_at", "edge", "expired_at"},
+		{"episodes", "edge", "episodes"},
+	}
+	for _, key := range keys {
+		fmt.Fprintf(w, "  <key id=\"%s\" for=\"%s\" attr.name=\"%s\" attr.type=\"string\"/>\n", key.id, key.scope, key.name)
+	}
+	for i, attribute := range graph.NodeAttributes {
+		fmt.Fprintf(w, "  <key id=\"a%d\" for=\"node\" attr.name=\"%s\" attr.type=\"%s\"/>\n", i, xmlText(attribute.Name), attribute.Type)
+	}
+	if graph.Partial() {
+		fmt.Fprintf(w, "  <key id=\"failed_episodes\" for=\"graph\" attr.name=\"failed_episodes\" attr.type=\"string\"/>\n")
+	}
+
+	fmt.Fprintf(w, "  <graph id=\"%s\" edgedefault=\"directed\">\n", xmlText(graph.UserID))
+	if note := graph.partialNote(); note != "" {
+		fmt.Fprintf(w, "    <desc>Graph of user %s exported %s. %s</desc>\n", xmlText(graph.UserID), graph.ExportedAt.Format(time.RFC3339), xmlText(note))
+		fmt.Fprintf(w, "    <data key=\"failed_episodes\">%s</data>\n", xmlText(strings.Join(graph.FailedEpisodes, ",")))
+	} else {
+		fmt.Fprintf(w, "    <desc>Graph of user %s exported %s</desc>\n", xmlText(graph.UserID), graph.ExportedAt.Format(time.RFC3339))
+	}
+	data := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "      <data key=\"%s\">%s</data>\n", key, xmlText(value))
+		}
+	}
+	for _, node := range graph.Nodes {
+		labels := graphLabels(node.Labels)
+		if labels != "" {
+			fmt.Fprintf(w, "    <node id=\"%s\" labels=\"%s\">\n", xmlText(node.UUID), xmlText(labels))
+		} else {
+			fmt.Fprintf(w, "    <node id=\"%s\">\n", xmlText(node.UUID))
+		}
+		data("label", node.Name)
+		data("labels", labels)
+		data("summary", node.Summary)
+		data("created_at", node.CreatedAt)
+		data("updated_at", node.UpdatedAt)
+		for i, attribute := range graph.NodeAttributes {
+			if value, ok := node.Attributes[attribute.Name]; ok {
+				data(fmt.Sprintf("a%d", i), attributeText(value))
+			}
+		}
+		fmt.Fprintf(w, "    </node>\n")
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(w, "    <edge id=\"%s\" source=\"%s\" target=\"%s\" label=\"%s\">\n",
+			xmlText(edge.UUID), xmlText(edge.SourceNodeUUID), xmlText(edge.TargetNodeUUID), xmlText(edge.Name))
+		data("e_label", edge.Name)
+		data("fact", edge.Fact)
+		data("e_created_at", edge.CreatedAt)
+		data("e_updated_at", edge.UpdatedAt)
+		data("valid_at", edge.ValidAt)
+		data("invalid_at", edge.InvalidAt)
+		data("expired_at", edge.ExpiredAt)
+		data("episodes", strings.Join(edge.Episodes, ","))
+		fmt.Fprintf(w, "    </edge>\n")
+	}
+	fmt.Fprintf(w, "  </graph>\n</graphml>\n")
+	return nil
+}
+
+// writeGEXF writes a static GEXF 1.3 graph for Gephi, with the node
+// attributes and edge validity as attribute columns
+func writeGEXF(w io.Writer, graph *exportGraph) error {
+	io.WriteString(w, xml.Header)
+	fmt.Fprintf(w, "<gexf xmlns=\"http://gexf.net/1.3\" version=\"1.3\">\n")
+	fmt.Fprintf(w, "  <meta lastmodifieddate=\"%s\">\n", graph.ExportedAt.Format("2006-01-02"))
+	fmt.Fprintf(w, "    <creator>Zep Web Interface</creator>\n")
+	if note := graph.partialNote(); note != "" {
+		fmt.Fprintf(w, "    <description>Graph of user %s. %s</description>\n", xmlText(graph.UserID), xmlText(note))
+	} else {
+		fmt.Fprintf(w, "    <description>Graph of user %s</description>\n", xmlText(graph.UserID))
+	}
+	fmt.Fprintf(w, "  </meta>\n")
+	fmt.Fprintf(w, "  <graph defaultedgetype=\"directed\" mode=\"static\">\n")
+
+	nodeColumns := []graphAttribute{
+		{Name: "labels", Type: "string"},
+		{Name: "summary", Type: "string"},
+		{Name: "created_at", Type: "string"},
+		{Name: "updated_at", Type: "string"},
+	}
+	edgeColumns := []string{"fact", "created_at", "updated_at", "valid_at", "invalid_at", "expired_at", "episodes"}
+
+	fmt.Fprintf(w, "    <attributes class=\"node\">\n")
+	for i, column := range nodeColumns {
+		fmt.Fprintf(w, "      <attribute id=\"n%d\" title=\"%s\" type=\"%s\"/>\n", i, column.Name, column.Type)
+	}
+	for i, attribute := range graph.NodeAttributes {
+		fmt.Fprintf(w, "      <attribute id=\"a%d\" title=\"%s\" type=\"%s\"/>\n", i, xmlText(attribute.Name), attribute.Type)
+	}
+	fmt.Fprintf(w, "    </attributes>\n")
+	fmt.Fprintf(w, "    <attributes class=\"edge\">\n")
+	for i, column := range edgeColumns {
+		fmt.Fprintf(w, "      <attribute id=\"e%d\" title=\"%s\" type=\"string\"/>\n", i, column)
+	}
+	fmt.Fprintf(w, "    </attributes>\n")
+
+	attvalue := func(id, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "          <attvalue for=\"%s\" value=\"%s\"/>\n", id, xmlText(value))
+		}
+	}
+	fmt.Fprintf(w, "    <nodes>\n")
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(w, "      <node id=\"%s\" label=\"%s\">\n", xmlText(node.UUID), xmlText(node.Name))
+		fmt.Fprintf(w, "        <attvalues>\n")
+		for i, value := range []string{strings.Join(node.Labels, ", "), node.Summary, node.CreatedAt, node.UpdatedAt} {
+			attvalue(fmt.Sprintf("n%d", i), value)
+		}
+		for i, attribute := range graph.NodeAttributes {
+			if value, ok := node.Attributes[attribute.Name]; ok {
+				attvalue(fmt.Sprintf("a%d", i), attributeText(value))
+			}
+		}
+		fmt.Fprintf(w, "        </attvalues>\n")
+		fmt.Fprintf(w, "      </node>\n")
+	}
+	fmt.Fprintf(w, "    </nodes>\n")
+	fmt.Fprintf(w, "    <edges>\n")
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(w, "      <edge id=\"%s\" source=\"%s\" target=\"%s\" label=\"%s\">\n",
+			xmlText(edge.UUID), xmlText(edge.SourceNodeUUID), xmlText(edge.TargetNodeUUID), xmlText(edge.Name))
+		fmt.Fprintf(w, "        <attvalues>\n")
+		for i, value := range []string{edge.Fact, edge.CreatedAt, edge.UpdatedAt, edge.ValidAt, edge.InvalidAt, edge.ExpiredAt, strings.Join(edge.Episodes, ",")} {
+			attvalue(fmt.Sprintf("e%d", i), value)
+		}
+		fmt.Fprintf(w, "        </attvalues>\n")
+		fmt.Fprintf(w, "      </edge>\n")
+	}
+	fmt.Fprintf(w, "    </edges>\n")
+	fmt.Fprintf(w, "  </graph>\n</gexf>\n")
+	return nil
+}
+
+// writeCypher writes one CREATE statement for the whole graph, so it can be
+// pasted into the Neo4j browser or sent as a single FalkorDB query.
+// Timestamps stay strings because FalkorDB has no datetime type.
+func writeCypher(w io.Writer, graph *exportGraph) error {
+	fmt.Fprintf(w, "// Graph of user %s exported %s\n", cypherComment(graph.UserID), graph.ExportedAt.Format(time.RFC3339))
+	fmt.Fprintf(w, "// %d nodes, %d edges\n", len(graph.Nodes), len(graph.Edges))
+	if note := graph.partialNote(); note != "" {
+		fmt.Fprintf(w, "// %s\n", cypherComment(note))
+	}
+	if len(graph.Nodes) == 0 {
+		return nil
+	}
+
+	variables := make(map[string]string, len(graph.Nodes))
+	fmt.Fprintf(w, "CREATE\n")
+	for i, node := range graph.Nodes {
+		variable := "n" + strconv.Itoa(i)
+		variables[node.UUID] = variable
+
+		properties := [][2]string{
+			{"uuid", cypherValue(node.UUID)},
+			{"name", cypherValue(node.Name)},
+		}
+		properties = appendCypherString(properties, "summary", node.Summary)
+		properties = appendCypherString(properties, "created_at", node.CreatedAt)
+		properties = appendCypherString(properties, "updated_at", node.UpdatedAt)
+		for _, attribute := range graph.NodeAttributes {
+			value, ok := node.Attributes[attribute.Name]
+			if !ok || value == nil || cypherReserved[attribute.Name] {
+				continue
+			}
+			properties = append(properties, [2]string{attribute.Name, cypherValue(value)})
+		}
+
+		labels := ""
+		for _, label := range node.Labels {
+			labels += ":" + cypherName(label)
+		}
+		separator := ","
+		if i == len(graph.Nodes)-1 && len(graph.Edges) == 0 {
+			separator = ";"
+		}
+		fmt.Fprintf(w, "  (%s%s %s)%s\n", variable, labels, cypherProperties(properties), separator)
+	}
+	for i, edge := range graph.Edges {
+		properties := [][2]string{{"uuid", cypherValue(edge.UUID)}}
+		properties = appendCypherString(properties, "fact", edge.Fact)
+		properties = appendCypherString(properties, "created_at", edge.CreatedAt)
+		properties = appendCypherString(properties, "updated_at", edge.UpdatedAt)
+		properties = appendCypherString(properties, "valid_at", edge.ValidAt)
+		properties = appendCypherString(properties, "invalid_at", edge.InvalidAt)
+		properties = appendCypherString(properties, "expired_at", edge.ExpiredAt)
+		if len(edge.Episodes) > 0 {
+			episodes := make([]string, len(edge.Episodes))
+			for i, episode := range edge.Episodes {
+				episodes[i] = cypherValue(episode)
+			}
+			properties = append(properties, [2]string{"episodes", "[" + strings.Join(episodes, ", ") + "]"})
+		}
+
+		relationship := edge.Name
+		if relationship == "" {
+			relationship = "RELATES_TO"
+		}
+		separator := ","
+		if i == len(graph.Edges)-1 {
+			separator = ";"
+		}
+		fmt.Fprintf(w, "  (%s)-[:%s %s]->(%s)%s\n", variables[edge.SourceNodeUUID], cypherName(relationship),
+			cypherProperties(properties), variables[edge.TargetNodeUUID], separator)
+	}
+	return nil
+}
+
+// cypherReserved are the node properties the export sets itself, which
+// attributes of the same name do not overwrite
+var cypherReserved = map[string]bool{"uuid": true, "name": true, "summary": true, "created_at": true, "updated_at": true}
+
+func appendCypherString(properties [][2]string, name, value string) [][2]string {
+	if value == "" {
+		return properties
+	}
+	return append(properties, [2]string{name, cypherValue(value)})
+}
+
+func cypherProperties(properties [][2]string) string {
+	parts := make([]string, len(properties))
+	for i, property := range properties {
+		parts[i] = cypherName(property[0]) + ": " + property[1]
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// cypherName quotes a label, relationship type or property key when it is
+// not a plain identifier
+func cypherName(name string) string {
+	plain := name != ""
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return name
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// cypherValue writes a property value as a Cypher literal. Lists and objects
+// become JSON strings since neither database stores nested maps.
+func cypherValue(value interface{}) string {
+	switch v := value.(type) {
+	case bool, float64:
+		return attributeText(v)
+	default:
+		replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+		return "'" + replacer.Replace(attributeText(v)) + "'"
+	}
+}
+
+func cypherComment(s string) string {
+	return strings.NewReplacer("\n", " ", "\r", " ").Replace(s)
+}
+
+// writeGraphCSV writes a zip with nodes.csv and edges.csv, plus
+// failed_episodes.csv for a partial graph. Labels and edge episodes are
+// separated by semicolons, the array delimiter neo4j-admin import expects,
+// and each node's attributes are kept as a JSON object.
+func writeGraphCSV(w io.Writer, graph *exportGraph) error {
+	archive := zip.NewWriter(w)
+
+	nodesFile, err := archive.CreateHeader(&zip.FileHeader{Name: "nodes.csv", Method: zip.Deflate, Modified: graph.ExportedAt})
+	if err != nil {
+		return err
+	}
+	nodes := csv.NewWriter(nodesFile)
+	nodes.Write([]string{"uuid", "name", "labels", "summary", "created_at", "updated_at", "attributes"})
+	for _, node := range graph.Nodes {
+		attributes := ""
+		if len(node.Attributes) > 0 {
+			encoded, err := attributeJSON(node.Attributes)
+			if err != nil {
+				return err
+			}
+			attributes = encoded
+		}
+		nodes.Write([]string{node.UUID, node.Name, strings.Join(node.Labels, ";"), node.Summary, node.CreatedAt, node.UpdatedAt, attributes})
+	}
+	nodes.Flush()
+	if err := nodes.Error(); err != nil {
+		return err
+	}
+
+	edgesFile, err := archive.CreateHeader(&zip.FileHeader{Name: "edges.csv", Method: zip.Deflate, Modified: graph.ExportedAt})
+	if err != nil {
+		return err
+	}
+	edges := csv.NewWriter(edgesFile)
+	edges.Write([]string{"uuid", "source_node_uuid", "target_node_uuid", "name", "fact", "created_at", "updated_at", "valid_at", "invalid_at", "expired_at", "episodes"})
+	for _, edge := range graph.Edges {
+		edges.Write([]string{edge.UUID, edge.SourceNodeUUID, edge.TargetNodeUUID, edge.Name, edge.Fact,
+			edge.CreatedAt, edge.UpdatedAt, edge.ValidAt, edge.InvalidAt, edge.ExpiredAt, strings.Join(edge.Episodes, ";")})
+	}
+	edges.Flush()
+	if err := edges.Error(); err != nil {
+		return err
+	}
+
+	if graph.Partial() {
+		failedFile, err := archive.CreateHeader(&zip.FileHeader{Name: "failed_episodes.csv", Method: zip.Deflate, Modified: graph.ExportedAt})
+		if err != nil {
+			return err
+		}
+		failed := csv.NewWriter(failedFile)
+		failed.Write([]string{"episode_uuid"})
+		for _, episode := range graph.FailedEpisodes {
+			failed.Write([]string{episode})
+		}
+		failed.Flush()
+		if err := failed.Error(); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+// awkward holds every character the export formats have to escape
+const awkward = "Ann <\"O'Brien\"> & `co`\\\nnext line\x01"
+
+func awkwardGraph(failed ...string) *exportGraph {
+	graph := newExportGraph("user <1>", &zepapi.Graph{
+		Nodes: []zepapi.GraphNode{
+			{UUID: "n1", Name: awkward, Labels: []string{"Entity", "Odd Label"}, Attributes: map[string]interface{}{"note": awkward, "vip": true}},
+			{UUID: "n2", Name: "Bob"},
+		},
+		Edges: []zepapi.GraphEpisode{
+			{UUID: "e1", SourceNodeUUID: "n1", TargetNodeUUID: "n2", Name: "KNOWS-WELL", Fact: awkward, Episodes: []string{"ep1", "ep2"}},
+		},
+		FailedEpisodes: failed,
+	})
+	graph.ExportedAt = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	return graph
+}
+
+// xmlValues parses an XML document and returns its attribute values and
+// text, failing the test when it is not well formed
+func xmlValues(t *testing.T, document []byte) []string {
+	t.Helper()
+	var values []string
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return values
+		}
+		if err != nil {
+			t.Fatalf("invalid XML: %v\n%s", err, document)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			for _, attr := range token.Attr {
+				values = append(values, attr.Value)
+			}
+		case xml.CharData:
+			if text := strings.TrimSpace(string(token)); text != "" {
+				values = append(values, text)
+			}
+		}
+	}
+}
+
+func TestGraphXMLExportsEscape(t *testing.T) {
+	// XML cannot hold the control character, so it is replaced
+	want := strings.Replace(awkward, "\x01", "\uFFFD", 1)
+
+	for _, format := range []string{"graphml", "gexf"} {
+		t.Run(format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := graphExportFormats[format].write(&out, awkwardGraph("ep3")); err != nil {
+				t.Fatal(err)
+			}
+			values := strings.Join(xmlValues(t, out.Bytes()), "\x00")
+			if count := strings.Count(values, want); count < 3 {
+				t.Errorf("%s holds the node name, note and fact %d times, want 3 or more", format, count)
+			}
+			if !strings.Contains(values, "user <1>") {
+				t.Errorf("%s lost the user ID", format)
+			}
+			if !strings.Contains(values, "Partial graph") {
+				t.Errorf("%s does not say the graph is partial", format)
+			}
+		})
+	}
+}
+
+func TestXMLText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{`<a href="x">&'`, "&lt;a href=&#34;x&#34;&gt;&amp;&#39;"},
+		{"tab\tnewline\n", "tab&#x9;newline&#xA;"},
+		{"bell\x07", "bell\uFFFD"},
+	}
+	for _, tt := range tests {
+		if got := xmlText(tt.in); got != tt.want {
+			t.Errorf("xmlText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCypherName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Entity", "Entity"},
+		{"_private2", "_private2"},
+		{"KNOWS-WELL", "`KNOWS-WELL`"},
+		{"Odd Label", "`Odd Label`"},
+		{"2fast", "`2fast`"},
+		{"tick`name", "`tick``name`"},
+		{"", "``"},
+	}
+	for _, tt := range tests {
+		if got := cypherName(tt.in); got != tt.want {
+			t.Errorf("cypherName(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCypherValue(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{"plain", "'plain'"},
+		{`it's \ done`, `'it\'s \\ done'`},
+		{"a\nb\r\tc", `'a\nb\r\tc'`},
+		{true, "true"},
+		{2.5, "2.5"},
+		{[]interface{}{"a", 1.0}, `'["a",1]'`},
+		{map[string]interface{}{"k": "it's"}, `'{"k":"it\'s"}'`},
+	}
+	for _, tt := range tests {
+		if got := cypherValue(tt.in); got != tt.want {
+			t.Errorf("cypherValue(%#v) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteCypher(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeCypher(&out, awkwardGraph("ep3\nMATCH (n) DETACH DELETE n")); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, "MATCH") {
+			t.Errorf("a failed episode ID escaped its comment: %q", line)
+		}
+	}
+	if last := lines[len(lines)-1]; !strings.HasSuffix(last, ";") {
+		t.Errorf("statement ends %q, want a semicolon", last)
+	}
+	for _, want := range []string{
+		`(n0:Entity:` + "`Odd Label`" + ` {uuid: 'n1', name: 'Ann <"O\'Brien"> & ` + "`co`" + `\\\nnext line` + "\x01" + `'`,
+		`vip: true`,
+		`(n0)-[:` + "`KNOWS-WELL`" + ` {uuid: 'e1'`,
+		`episodes: ['ep1', 'ep2']}]->(n1);`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Cypher export is missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+// NodeAttribute is one of a node's attributes, rendered as text
+type NodeAttribute struct {
+	Name  string
+	Value string
+}
+
+// NodeEdge is an edge into or out of the node being viewed, with the node
+// at its other end
+type NodeEdge struct {
+	zepapi.GraphEpisode
+	Other     zepapi.GraphNode
+	OtherURL  string
+	State     string // zepapi.EdgeValid, EdgeFuture or EdgeInvalid, as of now
+	ValidFrom string // formatted; empty when open
+	ValidTo   string
+	ExpiredAt string
+}
+
+// NodeEpisode is an episode that mentions the node. Episode is nil when the
+// episode is no longer in the user's episode list.
+type NodeEpisode struct {
+	ID          string
+	Episode     *zepapi.Episode
+	URL         string
+	EpisodesURL string
+	SessionURL  string
+}
+
+// UserGraphNode shows one entity of a user's graph: its summary, labels and
+// attributes, the edges into and out of it, and the episodes that mention it
+func (h *Handlers) UserGraphNode(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userId")
+	nodeID := chi.URLParam(r, "nodeId")
+
+	graph, loading, err := h.loadUserGraph(userID)
+	if err != nil {
+		log.Printf("❌ Failed to load graph of user %s: %v", userID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if loading != nil {
+		h.userGraphLoading(w, r, userID, "Entity", "", loading)
+		return
+	}
+
+	nodes := make(map[string]zepapi.GraphNode, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		nodes[node.UUID] = node
+	}
+	node, ok := nodes[nodeID]
+	if !ok {
+		http.Error(w, "Node not found in this user's graph", http.StatusNotFound)
+		return
+	}
+
+	graphPath := h.basePath + "/users/" + userID + "/graph"
+	now := time.Now()
+	var incoming, outgoing []NodeEdge
+	for _, edge := range graph.Edges {
+		var otherID string
+		switch nodeID {
+		case edge.SourceNodeUUID:
+			otherID = edge.TargetNodeUUID
+		case edge.TargetNodeUUID:
+			otherID = edge.SourceNodeUUID
+		default:
+			continue
+		}
+		nodeEdge := NodeEdge{
+			GraphEpisode: edge,
+			Other:        nodes[otherID],
+			OtherURL:     graphPath + "/nodes/" + otherID,
+			State:        edge.StateAt(now),
+			ValidFrom:    formatGraphTime(edge.ValidAt),
+			ValidTo:      formatGraphTime(edge.InvalidAt),
+			ExpiredAt:    formatGraphTime(edge.ExpiredAt),
+		}
+		// An edge from the node to itself is listed both ways
+		if edge.SourceNodeUUID == nodeID {
+			outgoing = append(outgoing, nodeEdge)
+		}
+		if edge.TargetNodeUUID == nodeID {
+			incoming = append(incoming, nodeEdge)
+		}
+	}
+
+	episodes, episodesErr := h.loadUserEpisodes(userID)
+	if episodesErr != nil {
+		log.Printf("⚠️ Failed to load episodes of user %s for node %s: %v", userID, nodeID, episodesErr)
+	}
+	mentions := h.nodeEpisodes(userID, node.Episodes, episodes)
+
+	data := map[string]interface{}{
+		"Title":    node.Name,
+		"SubTitle": "Entity in the knowledge graph of user " + userID,
+		"Page":     "user_graph_node",
+		"Path":     r.URL.Path,
+		"BreadCrumbs": []BreadCrumb{
+			{
+				Title: "Users",
+				Path:  h.basePath + "/users",
+			},
+			{
+				Title: "User Details",
+				Path:  h.basePath + "/users/" + userID,
+			},
+			{
+				Title: "Graph",
+				Path:  graphPath,
+			},
+			{
+				Title: node.Name,
+				Path:  r.URL.Path,
+			},
+		},
+		"UserID":     userID,
+		"Node":       node,
+		"CreatedAt":  formatGraphTime(node.CreatedAt),
+		"UpdatedAt":  formatGraphTime(node.UpdatedAt),
+		"Attributes": nodeAttributes(node.Attributes),
+		"Outgoing":   outgoing,
+		"Incoming":   incoming,
+		"Episodes":   mentions,
+		"FocusURL":   graphFocusURL(graphPath, node.UUID),
+		"MenuItems":  GetMenuItems(h.basePath),
+	}
+	if episodesErr != nil {
+		data["EpisodesError"] = episodesErr.Error()
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		if err := h.render(w, r, "UserGraphNodeContent", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		if err := h.render(w, r, "Layout", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// userGraphLoading renders a page for a request that needs the user's
+// graph while it is still loading. Without a downloadURL the page reloads
+// itself until the graph has loaded; with one it follows the load and then
+// starts the download.
+func (h *Handlers) userGraphLoading(w http.ResponseWriter, r *http.Request, userID, title, downloadURL string, progress *asyncProgress) {
+	graphPath := h.basePath + "/users/" + userID + "/graph"
+	data := map[string]interface{}{
+		"Title":    title,
+		"SubTitle": "Loading the knowledge graph of user " + userID,
+		"Page":     "user_graph_loading",
+		"Path":     r.URL.Path,
+		"BreadCrumbs": []BreadCrumb{
+			{
+				Title: "Users",
+				Path:  h.basePath + "/users",
+			},
+			{
+				Title: "User Details",
+				Path:  h.basePath + "/users/" + userID,
+			},
+			{
+				Title: "Graph",
+				Path:  graphPath,
+			},
+		},
+		"UserID":      userID,
+		"Progress":    progress.snapshot(),
+		"GraphPath":   graphPath,
+		"StatusURL":   h.basePath + "/api/users/" + userID + "/graph/async",
+		"DownloadURL": downloadURL,
+		"MenuItems":   GetMenuItems(h.basePath),
+	}
+
+	w.Header().Set("Retry-After", "2")
+	w.WriteHeader(http.StatusAccepted)
+	if r.Header.Get("HX-Request") == "true" {
+		if err := h.render(w, r, "UserGraphLoadingContent", data); err != nil {
+			log.Printf("❌ Failed to render graph loading page: %v", err)
+		}
+	} else {
+		if err := h.render(w, r, "Layout", data); err != nil {
+			log.Printf("❌ Failed to render graph loading page: %v", err)
+		}
+	}
+}
+
+// nodeAttributes lists a node's attributes by name
+func nodeAttributes(attributes map[string]interface{}) []NodeAttribute {
+	list := make([]NodeAttribute, 0, len(attributes))
+	for name, value := range attributes {
+		list = append(list, NodeAttribute{Name: name, Value: attributeText(value)})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// loadUserEpisodes returns the user's episodes from the cache, loading and
+// caching them like the episodes page does
+func (h *Handlers) loadUserEpisodes(userID string) ([]zepapi.Episode, error) {
+	cacheKey := fmt.Sprintf("episodes:%s", userID)
+	if cached, found := h.cache.Get(cacheKey); found && cached != nil {
+		if episodes, ok := cached.([]zepapi.Episode); ok {
+			return episodes, nil
+		}
+	}
+	episodes, err := h.apiClient.GetUserEpisodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	h.cache.Set(cacheKey, episodes, 15*time.Minute)
+	return episodes, nil
+}
+
+// nodeEpisodes looks up the episodes with the given IDs, oldest first, with
+// episodes that could not be found at the end
+func (h *Handlers) nodeEpisodes(userID string, ids []string, episodes []zepapi.Episode) []NodeEpisode {
+	byID := make(map[string]*zepapi.Episode, len(episodes))
+	for i := range episodes {
+		byID[episodes[i].EpisodeID] = &episodes[i]
+	}
+
+	mentions := make([]NodeEpisode, 0, len(ids))
+	for _, id := range ids {
+		mention := NodeEpisode{
+			ID:          id,
+			Episode:     byID[id],
+			URL:         h.basePath + "/users/" + userID + "/episodes/" + id,
+			EpisodesURL: h.basePath + "/users/" + userID + "/episodes#episode-" + id,
+		}
+		if mention.Episode != nil && mention.Episode.SessionID != "" {
+			mention.SessionURL = h.basePath + "/sessions/" + mention.Episode.SessionID
+		}
+		mentions = append(mentions, mention)
+	}
+	sort.SliceStable(mentions, func(i, j int) bool {
+		a, b := mentions[i].Episode, mentions[j].Episode
+		if a == nil || a.CreatedAt == nil {
+			return false
+		}
+		if b == nil || b.CreatedAt == nil {
+			return true
+		}
+		return a.CreatedAt.Before(*b.CreatedAt)
+	})
+	return mentions
+}
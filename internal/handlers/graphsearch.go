@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+const (
+	// defaultGraphSearchLimit is how many results a graph search asks for
+	// unless told otherwise; Zep allows up to maxGraphSearchLimit
+	defaultGraphSearchLimit = 10
+	maxGraphSearchLimit     = 50
+)
+
+// graphSearchScopes lists the scopes in the order the search form offers them
+var graphSearchScopes = []struct {
+	Scope string
+	Label string
+}{
+	{Scope: zepapi.GraphScopeEdges, Label: "Facts (edges)"},
+	{Scope: zepapi.GraphScopeNodes, Label: "Entities (nodes)"},
+}
+
+// GraphSearchNode is a node found by a graph search
+type GraphSearchNode struct {
+	*zepapi.EntityNode
+	Score    string // formatted; empty when Zep gave none
+	FocusURL string
+}
+
+// GraphSearchEdge is an edge found by a graph search
+type GraphSearchEdge struct {
+	*zepapi.EntityEdge
+	Score          string
+	SourceFocusURL string
+	TargetFocusURL string
+}
+
+// UserGraphSearch searches a user's graph with Zep's graph search and links
+// each result to the graph view focused on it
+func (h *Handlers) UserGraphSearch(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userId")
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	scope := r.URL.Query().Get("scope")
+	if scope != zepapi.GraphScopeNodes {
+		scope = zepapi.GraphScopeEdges
+	}
+	limit := defaultGraphSearchLimit
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+		limit = n
+	}
+	if limit > maxGraphSearchLimit {
+		limit = maxGraphSearchLimit
+	}
+
+	graphPath := h.basePath + "/users/" + userID + "/graph"
+	data := map[string]interface{}{
+		"Title":    "Graph Search",
+		"SubTitle": "Search the knowledge graph of user " + userID,
+		"Page":     "user_graph_search",
+		"Path":     r.URL.Path,
+		"BreadCrumbs": []BreadCrumb{
+			{
+				Title: "Users",
+				Path:  h.basePath + "/users",
+			},
+			{
+				Title: "User Details",
+				Path:  h.basePath + "/users/" + userID,
+			},
+			{
+				Title: "Graph",
+				Path:  graphPath,
+			},
+			{
+				Title: "Search",
+				Path:  r.URL.Path,
+			},
+		},
+		"UserID":    userID,
+		"Query":     query,
+		"Scope":     scope,
+		"Scopes":    graphSearchScopes,
+		"Limit":     limit,
+		"MaxLimit":  maxGraphSearchLimit,
+		"GraphPath": graphPath,
+		"MenuItems": GetMenuItems(h.basePath),
+	}
+
+	if query != "" {
+		results, err := h.apiClient.SearchGraph(zepapi.GraphSearch{Query: query, UserID: userID, Scope: scope, Limit: limit})
+		if err != nil {
+			log.Printf("❌ Graph search for user %s failed: %v", userID, err)
+			data["Error"] = err.Error()
+		} else {
+			nodes := make([]GraphSearchNode, 0, len(results.Nodes))
+			for _, node := range results.Nodes {
+				nodes = append(nodes, GraphSearchNode{
+					EntityNode: node,
+					Score:      formatScore(node.Score),
+					FocusURL:   graphFocusURL(graphPath, node.UUID),
+				})
+			}
+			edges := make([]GraphSearchEdge, 0, len(results.Edges))
+			for _, edge := range results.Edges {
+				edges = append(edges, GraphSearchEdge{
+					EntityEdge:     edge,
+					Score:          formatScore(edge.Score),
+					SourceFocusURL: graphFocusURL(graphPath, edge.SourceNodeUUID),
+					TargetFocusURL: graphFocusURL(graphPath, edge.TargetNodeUUID),
+				})
+			}
+			data["Nodes"] = nodes
+			data["Edges"] = edges
+		}
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		if err := h.render(w, r, "UserGraphSearchContent", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		if err := h.render(w, r, "Layout", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// graphFocusURL opens the graph view centred on a node
+func graphFocusURL(graphPath, nodeUUID string) string {
+	return graphPath + "?" + url.Values{"focus": {nodeUUID}}.Encode()
+}
+
+func formatScore(score *float64) string {
+	if score == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*score, 'f', 3, 64)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+// graphSearchTemplate prints what UserGraphSearch hands the page
+const graphSearchTemplate = `{{ define "Layout" }}scope={{ .Scope }} limit={{ .Limit }}
+{{ range .Nodes }}node {{ .Name }} {{ .Score }} {{ .FocusURL }}
+{{ end }}{{ range .Edges }}edge {{ .Fact }} {{ .Score }} {{ .SourceFocusURL }} {{ .TargetFocusURL }}
+{{ end }}{{ with .Error }}error {{ . }}{{ end }}{{ end }}`
+
+func TestUserGraphSearch(t *testing.T) {
+	var sent map[string]interface{}
+	zep := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/graph/search" {
+			http.NotFound(w, r)
+			return
+		}
+		sent = nil
+		json.NewDecoder(r.Body).Decode(&sent)
+		score := 0.91234
+		results := zepapi.GraphSearchResults{}
+		if sent["scope"] == zepapi.GraphScopeNodes {
+			results.Nodes = []*zepapi.EntityNode{{UUID: "n/1", Name: "Ann", Score: &score}, {UUID: "n2", Name: "Bob"}}
+		} else {
+			results.Edges = []*zepapi.EntityEdge{{UUID: "e1", SourceNodeUUID: "n/1", TargetNodeUUID: "n2", Fact: "knows", Score: &score}}
+		}
+		json.NewEncoder(w).Encode(results)
+	}))
+	defer zep.Close()
+
+	h := &Handlers{
+		apiClient: zepapi.NewClient(zep.URL, "test-key", ""),
+		templates: template.Must(template.New("").Parse(graphSearchTemplate)),
+		basePath:  "/admin",
+	}
+	router := chi.NewRouter()
+	router.Get("/admin/users/{userId}/graph/search", h.UserGraphSearch)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantSent  map[string]interface{} // nil when no search may run
+		wantLines []string
+	}{
+		{
+			name:      "edges by default",
+			query:     "q=who+knows&scope=bogus",
+			wantSent:  map[string]interface{}{"query": "who knows", "user_id": "u1", "scope": "edges", "limit": 10.0},
+			wantLines: []string{"scope=edges limit=10", "edge knows 0.912 /admin/users/u1/graph?focus=n%2F1 /admin/users/u1/graph?focus=n2"},
+		},
+		{
+			name:      "nodes with the limit capped",
+			query:     "q=ann&scope=nodes&limit=500",
+			wantSent:  map[string]interface{}{"query": "ann", "user_id": "u1", "scope": "nodes", "limit": 50.0},
+			wantLines: []string{"scope=nodes limit=50", "node Ann 0.912 /admin/users/u1/graph?focus=n%2F1", "node Bob  /admin/users/u1/graph?focus=n2"},
+		},
+		{
+			name:      "no query",
+			query:     "q=+&limit=-3",
+			wantLines: []string{"scope=edges limit=10"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent = nil
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/users/u1/graph/search?"+tt.query, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+			if tt.wantSent == nil && sent != nil {
+				t.Errorf("searched with %v, want no search", sent)
+			}
+			for key, want := range tt.wantSent {
+				if sent[key] != want {
+					t.Errorf("sent %s = %v, want %v", key, sent[key], want)
+				}
+			}
+			got := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+			if strings.Join(got, "\n") != strings.Join(tt.wantLines, "\n") {
+				t.Errorf("page =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.wantLines, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+// asOfLayouts are the forms ?as_of= accepts: a full timestamp, the value of
+// a datetime-local input, or a date meaning midnight UTC
+var asOfLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// parseAsOf reads an ?as_of= value. Times without a zone are taken as UTC;
+// an empty value gives the zero time, meaning now.
+func parseAsOf(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range asOfLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("as_of must be a timestamp such as 2024-05-01T12:00:00Z or a date such as 2024-05-01")
+}
+
+// parseShowInvalid reads an ?invalid= value: "show" (the default) keeps
+// the facts invalidated by the as-of instant, greyed out, and "hide" drops them
+func parseShowInvalid(value string) (bool, error) {
+	switch value {
+	case "", "show":
+		return true, nil
+	case "hide":
+		return false, nil
+	}
+	return true, fmt.Errorf("invalid must be show or hide")
+}
+
+func formatAsOf(asOf time.Time) string {
+	if asOf.IsZero() {
+		return ""
+	}
+	return asOf.Format(time.RFC3339)
+}
+
+// formatGraphTime formats a graph timestamp for display, leaving it as it
+// is when it cannot be read
+func formatGraphTime(value string) string {
+	if t, ok := zepapi.ParseGraphTime(value); ok {
+		return t.Format("Jan 2, 2006 3:04 PM")
+	}
+	return value
+}
+
+// filterGraphAsOf keeps the edges whose fact held at asOf, and the edges
+// invalidated by then too when showInvalid is set. Nodes created after asOf
+// are dropped along with their edges. A zero asOf keeps everything.
+func filterGraphAsOf(graph *zepapi.Graph, asOf time.Time, showInvalid bool) *zepapi.Graph {
+	if asOf.IsZero() {
+		return graph
+	}
+	kept := &zepapi.Graph{
+		Nodes:          make([]zepapi.GraphNode, 0, len(graph.Nodes)),
+		Edges:          make([]zepapi.GraphEpisode, 0, len(graph.Edges)),
+		FailedEpisodes: graph.FailedEpisodes,
+	}
+	keptNodes := make(map[string]bool, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		if created, ok := zepapi.ParseGraphTime(node.CreatedAt); ok && created.After(asOf) {
+			continue
+		}
+		keptNodes[node.UUID] = true
+		kept.Nodes = append(kept.Nodes, node)
+	}
+	for _, edge := range graph.Edges {
+		if !keptNodes[edge.SourceNodeUUID] || !keptNodes[edge.TargetNodeUUID] {
+			continue
+		}
+		switch edge.StateAt(asOf) {
+		case zepapi.EdgeValid:
+			kept.Edges = append(kept.Edges, edge)
+		case zepapi.EdgeInvalid:
+			if showInvalid {
+				kept.Edges = append(kept.Edges, edge)
+			}
+		}
+	}
+	return kept
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+func TestParseAsOf(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "", want: time.Time{}},
+		{value: "2024-05-01T12:30:00Z", want: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)},
+		{value: "2024-05-01T12:30:00.5+02:00", want: time.Date(2024, 5, 1, 10, 30, 0, 5e8, time.UTC)},
+		{value: "2024-05-01T12:30:15", want: time.Date(2024, 5, 1, 12, 30, 15, 0, time.UTC)},
+		{value: "2024-05-01T12:30", want: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)},
+		{value: "2024-05-01", want: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "yesterday", wantErr: true},
+		{value: "2024-13-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseAsOf(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAsOf(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) || (!got.IsZero() && got.Location() != time.UTC) {
+			t.Errorf("parseAsOf(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFilterGraphAsOf(t *testing.T) {
+	graph := &zepapi.Graph{
+		Nodes: []zepapi.GraphNode{
+			{UUID: "ann", CreatedAt: "2024-01-01T00:00:00Z"},
+			{UUID: "bob", CreatedAt: "2024-01-01T00:00:00Z"},
+			{UUID: "cid", CreatedAt: "2024-06-01T00:00:00Z"},
+			{UUID: "dee"}, // no timestamp is always kept
+		},
+		Edges: []zepapi.GraphEpisode{
+			{UUID: "holds", SourceNodeUUID: "ann", TargetNodeUUID: "bob", CreatedAt: "2024-01-01T00:00:00Z", ValidAt: "2024-02-01T00:00:00Z"},
+			{UUID: "not-yet-valid", SourceNodeUUID: "ann", TargetNodeUUID: "bob", CreatedAt: "2024-01-01T00:00:00Z", ValidAt: "2024-04-01T00:00:00Z"},
+			{UUID: "not-yet-known", SourceNodeUUID: "ann", TargetNodeUUID: "bob", CreatedAt: "2024-04-01T00:00:00Z"},
+			{UUID: "invalidated", SourceNodeUUID: "ann", TargetNodeUUID: "bob", CreatedAt: "2024-01-01T00:00:00Z", InvalidAt: "2024-02-01T00:00:00Z"},
+			{UUID: "expired", SourceNodeUUID: "bob", TargetNodeUUID: "ann", CreatedAt: "2024-01-01T00:00:00Z", ExpiredAt: "2024-03-01T00:00:00Z"},
+			{UUID: "invalidated-later", SourceNodeUUID: "ann", TargetNodeUUID: "bob", CreatedAt: "2024-01-01T00:00:00Z", InvalidAt: "2024-05-01T00:00:00Z"},
+			{UUID: "to-future-node", SourceNodeUUID: "ann", TargetNodeUUID: "cid", CreatedAt: "2024-01-01T00:00:00Z"},
+			{UUID: "undated", SourceNodeUUID: "dee", TargetNodeUUID: "ann"},
+		},
+		FailedEpisodes: []string{"ep3"},
+	}
+	asOf := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		asOf        time.Time
+		showInvalid bool
+		wantNodes   []string
+		wantEdges   []string
+	}{
+		{"now", time.Time{}, false, []string{"ann", "bob", "cid", "dee"}, []string{"holds", "not-yet-valid", "not-yet-known", "invalidated", "expired", "invalidated-later", "to-future-node", "undated"}},
+		{"showing invalid", asOf, true, []string{"ann", "bob", "dee"}, []string{"holds", "invalidated", "expired", "invalidated-later", "undated"}},
+		{"hiding invalid", asOf, false, []string{"ann", "bob", "dee"}, []string{"holds", "invalidated-later", "undated"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filterGraphAsOf(graph, tt.asOf, tt.showInvalid)
+			var nodes, edges []string
+			for _, node := range got.Nodes {
+				nodes = append(nodes, node.UUID)
+			}
+			for _, edge := range got.Edges {
+				edges = append(edges, edge.UUID)
+			}
+			if !reflect.DeepEqual(nodes, tt.wantNodes) {
+				t.Errorf("nodes = %v, want %v", nodes, tt.wantNodes)
+			}
+			if !reflect.DeepEqual(edges, tt.wantEdges) {
+				t.Errorf("edges = %v, want %v", edges, tt.wantEdges)
+			}
+			if !got.Partial() {
+				t.Error("the filtered graph lost its failed episodes")
+			}
+		})
+	}
+}
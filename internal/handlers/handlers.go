@@ -2,17 +2,21 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/schizoidcock/zep-web-interface/internal/audit"
+	"github.com/schizoidcock/zep-web-interface/internal/auth"
 	"github.com/schizoidcock/zep-web-interface/internal/cache"
 	"github.com/schizoidcock/zep-web-interface/internal/config"
 	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
@@ -24,6 +28,8 @@ type Handlers struct {
 	basePath  string
 	cache     *cache.Cache
 	config    *config.Config
+	auth      *auth.Authenticator
+	auditLog  *audit.Log
 }
 
 // Data structures matching Zep v0.27 template expectations
@@ -44,6 +50,11 @@ type TableData struct {
 	PageCount   int           `json:"page_count"`
 	OrderBy     string        `json:"order_by"`
 	Asc         bool          `json:"asc"`
+	Selectable  bool          `json:"selectable"` // rows get checkboxes for bulk actions
+	// TotalUnknown is set when the server reported no total. TotalCount is
+	// then the rows seen so far and PageCount has one more page while pages
+	// come back full.
+	TotalUnknown bool `json:"total_unknown"`
 }
 
 type BreadCrumb struct {
@@ -66,7 +77,10 @@ type PageData struct {
 	Path        string        `json:"path"`
 	BreadCrumbs []BreadCrumb  `json:"breadcrumbs"`
 	Data        *TableData    `json:"data"`
+	Search      string        `json:"search,omitempty"`
 	MenuItems   []MenuItem    `json:"menu_items"`
+	Permissions Permissions   `json:"-"`
+	CSRFToken   string        `json:"-"`
 }
 
 // SessionRow represents a session with timestamp formatting
@@ -115,7 +129,97 @@ var UserTableColumns = []Column{
 	},
 }
 
-func New(apiClient *zepapi.Client, templates *template.Template, basePath string, cfg *config.Config) *Handlers {
+// defaultPageSize is the number of rows shown per table page
+const defaultPageSize = 10
+
+// tableQuery holds the paging, ordering and search parameters of a table request
+type tableQuery struct {
+	Page     int
+	PageSize int
+	OrderBy  string
+	Asc      bool
+	Search   string
+}
+
+// parseTableQuery reads page, order, asc and q from the query string. Only order
+// keys of sortable columns are accepted; anything else falls back to created_at.
+func parseTableQuery(r *http.Request, columns []Column) tableQuery {
+	query := tableQuery{
+		Page:     1,
+		PageSize: defaultPageSize,
+		OrderBy:  "created_at",
+	}
+
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
+			query.Page = page
+		}
+	}
+
+	if order := r.URL.Query().Get("order"); order != "" {
+		for _, col := range columns {
+			if col.Sortable && col.OrderByKey == order {
+				query.OrderBy = order
+				break
+			}
+		}
+	}
+
+	if ascStr := r.URL.Query().Get("asc"); ascStr == "true" {
+		query.Asc = true
+	}
+
+	query.Search = strings.TrimSpace(r.URL.Query().Get("q"))
+
+	return query
+}
+
+// tablePath returns the request path carrying the current ordering, so the
+// pager can append "&page=N" and the column headers can split off the query
+func tablePath(r *http.Request, query tableQuery) string {
+	values := url.Values{}
+	values.Set("order", query.OrderBy)
+	values.Set("asc", strconv.FormatBool(query.Asc))
+	if query.Search != "" {
+		values.Set("q", query.Search)
+	}
+	return r.URL.Path + "?" + values.Encode()
+}
+
+// newTableData builds the table state for one page of rows. A total of
+// zepapi.UnknownTotal pages on as long as pages come back full.
+func newTableData(tableID string, columns []Column, rows interface{}, rowCount, totalCount int, query tableQuery) *TableData {
+	totalUnknown := false
+	if totalCount == zepapi.UnknownTotal {
+		// A short page is the last one, so only a full page leaves it open
+		totalCount = (query.Page-1)*query.PageSize + rowCount
+		totalUnknown = rowCount >= query.PageSize
+	}
+	pageCount := (totalCount + query.PageSize - 1) / query.PageSize
+	if totalUnknown {
+		pageCount++
+	}
+	if pageCount == 0 {
+		pageCount = 1
+	}
+
+	return &TableData{
+		TableID:     tableID,
+		Columns:     columns,
+		Rows:        rows,
+		TotalCount:  totalCount,
+		RowCount:    rowCount,
+		CurrentPage: query.Page,
+		PageSize:    query.PageSize,
+		PageCount:   pageCount,
+		OrderBy:     query.OrderBy,
+		Asc:         query.Asc,
+
+		TotalUnknown: totalUnknown,
+	}
+}
+
+func New(apiClient *zepapi.Client, templates *template.Template, basePath string, cfg *config.Config, authenticator *auth.Authenticator, auditLog *audit.Log) *Handlers {
 	if basePath == "" {
 		basePath = "/admin"
 	}
@@ -125,6 +229,8 @@ func New(apiClient *zepapi.Client, templates *template.Template, basePath string
 		basePath:  basePath,
 		cache:     cache.NewCache(),
 		config:    cfg,
+		auth:      authenticator,
+		auditLog:  auditLog,
 	}
 }
 
@@ -158,12 +264,12 @@ func (h *Handlers) Dashboard(w http.ResponseWriter, r *http.Request) {
 	
 	// Check if this is an HTMX request, if so render only the content
 	if r.Header.Get("HX-Request") == "true" {
-		if err := h.templates.ExecuteTemplate(w, "DashboardContent", data); err != nil {
+		if err := h.render(w, r, "DashboardContent", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	} else {
-		if err := h.templates.ExecuteTemplate(w, "Layout", data); err != nil {
+		if err := h.render(w, r, "Layout", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -172,99 +278,81 @@ func (h *Handlers) Dashboard(w http.ResponseWriter, r *http.Request) {
 
 // SessionList handles the sessions list page
 func (h *Handlers) SessionList(w http.ResponseWriter, r *http.Request) {
-	sessions, err := h.apiClient.GetSessions()
+	query := parseTableQuery(r, SessionTableColumns)
+
+	permissions := h.permissions(r)
+	tableData, err := h.sessionTableData(query)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	// Parse query parameters for sorting and pagination
-	currentPage := 1
-	pageSize := 10
-	orderBy := "created_at"
-	asc := false
-
-	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
-		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
-			currentPage = page
-		}
-	}
-
-	if order := r.URL.Query().Get("order"); order != "" {
-		orderBy = order
-	}
-
-	if ascStr := r.URL.Query().Get("asc"); ascStr == "true" {
-		asc = true
-	}
-
-	// Convert sessions to SessionRows for template compatibility
-	sessionRows := make([]SessionRow, len(sessions))
-	for i := range sessions {
-		sessionRows[i] = SessionRow{Session: &sessions[i]}
-	}
-
-	// Calculate pagination
-	totalCount := len(sessions)
-	pageCount := (totalCount + pageSize - 1) / pageSize
-	rowCount := len(sessionRows)
-
-	// Create table data structure
-	tableData := &TableData{
-		TableID:     "session-table",
-		Columns:     SessionTableColumns,
-		Rows:        sessionRows,
-		TotalCount:  totalCount,
-		RowCount:    rowCount,
-		CurrentPage: currentPage,
-		PageSize:    pageSize,
-		PageCount:   pageCount,
-		OrderBy:     orderBy,
-		Asc:         asc,
-	}
+	tableData.Selectable = permissions.CanDelete
 
 	// Create page data with breadcrumbs
 	pageData := &PageData{
 		Title:    "Sessions",
 		SubTitle: "View and manage sessions",
 		Page:     "sessions",
-		Path:     r.URL.Path,
+		Path:     tablePath(r, query),
 		BreadCrumbs: []BreadCrumb{
 			{
 				Title: "Sessions",
 				Path:  h.basePath + "/sessions",
 			},
 		},
-		Data:      tableData,
-		MenuItems: GetMenuItems(h.basePath),
+		Data:        tableData,
+		MenuItems:   GetMenuItems(h.basePath),
+		Permissions: permissions,
 	}
 
 	// Check if this is an HTMX request, if so render only the content
 	if r.Header.Get("HX-Request") == "true" {
-		if err := h.templates.ExecuteTemplate(w, "SessionsContent", pageData); err != nil {
+		if err := h.render(w, r, "SessionsContent", pageData); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	} else {
-		if err := h.templates.ExecuteTemplate(w, "Layout", pageData); err != nil {
+		if err := h.render(w, r, "Layout", pageData); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	}
 }
 
+// sessionTableData fetches one page of sessions and wraps it for the session table
+func (h *Handlers) sessionTableData(query tableQuery) (*TableData, error) {
+	page, err := h.apiClient.GetSessionsPage(zepapi.ListOptions{
+		PageNumber: query.Page,
+		PageSize:   query.PageSize,
+		OrderBy:    query.OrderBy,
+		Asc:        query.Asc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert sessions to SessionRows for template compatibility
+	sessionRows := make([]SessionRow, len(page.Sessions))
+	for i := range page.Sessions {
+		sessionRows[i] = SessionRow{Session: &page.Sessions[i]}
+	}
+
+	return newTableData("session-table", SessionTableColumns, sessionRows, len(sessionRows), page.Total, query), nil
+}
+
 // SessionDetails handles the session details page
 func (h *Handlers) SessionDetails(w http.ResponseWriter, r *http.Request) {
 	sessionID := chi.URLParam(r, "sessionId")
 	
 	// Parse query parameters for message pagination
-	currentPage := 1
-	pageSize := 10
+	currentPage := 0
+	pageSize := defaultPageSize
 	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
 		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
 			currentPage = page
 		}
 	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
 	
 	// Fetch session details
 	session, err := h.apiClient.GetSession(sessionID)
@@ -273,6 +361,25 @@ func (h *Handlers) SessionDetails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Search the whole session, then open the first page with a match unless
+	// a page was asked for
+	var matches []SearchResult
+	var searchErr string
+	if query != "" {
+		found, err := h.apiClient.ScanMessages([]string{sessionID}, query, searchLimit)
+		if err != nil {
+			searchErr = err.Error()
+		} else {
+			matches = h.searchResults(found, query)
+			if currentPage == 0 && len(matches) > 0 {
+				currentPage = matches[0].Page
+			}
+		}
+	}
+	if currentPage == 0 {
+		currentPage = 1
+	}
+
 	// Fetch message list for this session
 	messages, totalMessages, err := h.apiClient.GetMessageList(sessionID, currentPage, pageSize)
 	if err != nil {
@@ -322,6 +429,8 @@ func (h *Handlers) SessionDetails(w http.ResponseWriter, r *http.Request) {
 		"Path":       pageData.Path,
 		"BreadCrumbs": pageData.BreadCrumbs,
 		"MenuItems":  pageData.MenuItems,
+		"Permissions": h.permissions(r),
+		"ExportFormats": sessionExportLinks,
 		"Data": map[string]interface{}{
 			"Session":     session,
 			"Messages":    messages,
@@ -330,17 +439,23 @@ func (h *Handlers) SessionDetails(w http.ResponseWriter, r *http.Request) {
 			"CurrentPage": currentPage,
 			"PageCount":   pageCount,
 			"PageSize":    pageSize,
+			"FirstIndex":  (currentPage - 1) * pageSize,
+			"PagerPath":   sessionPagerPath(r.URL.Path, query),
+			"Search":      query,
+			"Matches":     matches,
+			"SearchError": searchErr,
+			"SearchLimit": searchLimit,
 		},
 	}
 	
 	// Check if this is an HTMX request, if so render only the content
 	if r.Header.Get("HX-Request") == "true" {
-		if err := h.templates.ExecuteTemplate(w, "SessionDetailsContent", data); err != nil {
+		if err := h.render(w, r, "SessionDetailsContent", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	} else {
-		if err := h.templates.ExecuteTemplate(w, "Layout", data); err != nil {
+		if err := h.render(w, r, "Layout", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -352,6 +467,7 @@ func (h *Handlers) DeleteSession(w http.ResponseWriter, r *http.Request) {
 	sessionID := chi.URLParam(r, "sessionId")
 	
 	err := h.apiClient.DeleteSession(sessionID)
+	h.recordAudit(h.auditEntry(r, "session.delete", "session", sessionID), err)
 	if err != nil {
 		// Return JSON error response for HTMX requests
 		if r.Header.Get("HX-Request") == "true" {
@@ -386,88 +502,81 @@ func (h *Handlers) DeleteSession(w http.ResponseWriter, r *http.Request) {
 
 // UserList handles the users list page
 func (h *Handlers) UserList(w http.ResponseWriter, r *http.Request) {
-	users, err := h.apiClient.GetUsersWithSessionCounts()
+	query := parseTableQuery(r, UserTableColumns)
+
+	permissions := h.permissions(r)
+	tableData, err := h.userTableData(query)
 	if err != nil {
 		// Log the specific error for debugging
-		log.Printf("❌ GetUsersWithSessionCounts error: %v", err)
+		log.Printf("❌ Failed to get users: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to get users: %v", err), http.StatusInternalServerError)
 		return
 	}
-	
-	log.Printf("✅ Successfully fetched %d users", len(users))
-
-	// Parse query parameters for sorting and pagination
-	currentPage := 1
-	pageSize := 10
-	orderBy := "created_at"
-	asc := false
-
-	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
-		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
-			currentPage = page
-		}
-	}
-
-	if order := r.URL.Query().Get("order"); order != "" {
-		orderBy = order
-	}
-
-	if ascStr := r.URL.Query().Get("asc"); ascStr == "true" {
-		asc = true
-	}
-
-	// Note: Session counts are now fetched concurrently via GetUsersWithSessionCounts
-	// This eliminates the N+1 query problem
-
-	// Calculate pagination
-	totalCount := len(users)
-	pageCount := (totalCount + pageSize - 1) / pageSize
-	rowCount := len(users)
-
-	// Create table data structure
-	tableData := &TableData{
-		TableID:     "user-table",
-		Columns:     UserTableColumns,
-		Rows:        users, // Users slice directly
-		TotalCount:  totalCount,
-		RowCount:    rowCount,
-		CurrentPage: currentPage,
-		PageSize:    pageSize,
-		PageCount:   pageCount,
-		OrderBy:     orderBy,
-		Asc:         asc,
-	}
+	tableData.Selectable = permissions.CanDelete
 
 	// Create page data with breadcrumbs
 	pageData := &PageData{
 		Title:    "Users",
 		SubTitle: "View and manage users",
 		Page:     "users",
-		Path:     r.URL.Path,
+		Path:     tablePath(r, query),
 		BreadCrumbs: []BreadCrumb{
 			{
 				Title: "Users",
 				Path:  h.basePath + "/users",
 			},
 		},
-		Data:      tableData,
-		MenuItems: GetMenuItems(h.basePath),
+		Data:        tableData,
+		Search:      query.Search,
+		MenuItems:   GetMenuItems(h.basePath),
+		Permissions: permissions,
 	}
 
 	// Check if this is an HTMX request, if so render only the content
 	if r.Header.Get("HX-Request") == "true" {
-		if err := h.templates.ExecuteTemplate(w, "UsersContent", pageData); err != nil {
+		if err := h.render(w, r, "UsersContent", pageData); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	} else {
-		if err := h.templates.ExecuteTemplate(w, "Layout", pageData); err != nil {
+		if err := h.render(w, r, "Layout", pageData); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	}
 }
 
+// userTableData fetches one page of users, searched if a query is given, and
+// wraps it for the user table. Zep can neither search users nor order them
+// by session count, so those requests page through the cached user list.
+func (h *Handlers) userTableData(query tableQuery) (*TableData, error) {
+	opts := zepapi.ListOptions{
+		PageNumber: query.Page,
+		PageSize:   query.PageSize,
+		OrderBy:    query.OrderBy,
+		Asc:        query.Asc,
+	}
+
+	var page *zepapi.UsersResponse
+	if query.Search != "" || query.OrderBy == "session_count" {
+		users, err := h.allUsers()
+		if err != nil {
+			return nil, err
+		}
+		page = zepapi.SearchUsers(users, query.Search, opts)
+	} else {
+		var err error
+		page, err = h.apiClient.GetUsersPage(opts)
+		if err != nil {
+			return nil, err
+		}
+		h.apiClient.FillSessionCounts(page.Users)
+	}
+
+	log.Printf("✅ Successfully fetched %d of %d users", len(page.Users), page.Total)
+	return newTableData("user-table", UserTableColumns, page.Users, len(page.Users), page.Total, query), nil
+}
+
 // UserDetails handles the user details page
 func (h *Handlers) UserDetails(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userId")
@@ -526,16 +635,17 @@ func (h *Handlers) UserDetails(w http.ResponseWriter, r *http.Request) {
 		"User": user, // User data separately for form access
 		"MenuItems": GetMenuItems(h.basePath),
 		"Slug": userID, // Add slug for Alpine.js functionality
+		"Permissions": h.permissions(r),
 	}
 	
 	// Check if this is an HTMX request, if so render only the content
 	if r.Header.Get("HX-Request") == "true" {
-		if err := h.templates.ExecuteTemplate(w, "UserDetailsContent", data); err != nil {
+		if err := h.render(w, r, "UserDetailsContent", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	} else {
-		if err := h.templates.ExecuteTemplate(w, "Layout", data); err != nil {
+		if err := h.render(w, r, "Layout", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -597,7 +707,7 @@ func (h *Handlers) UserSessions(w http.ResponseWriter, r *http.Request) {
 		"UserID": userID,
 	}
 	
-	if err := h.templates.ExecuteTemplate(w, "Layout", data); err != nil {
+	if err := h.render(w, r, "Layout", data); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -606,8 +716,23 @@ func (h *Handlers) UserSessions(w http.ResponseWriter, r *http.Request) {
 // UserEpisodes handles the user episodes page with async loading
 func (h *Handlers) UserEpisodes(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userId")
+
+	// Render cached episodes straight away, otherwise let the page poll the
+	// async loader and swap in the episodes API fragment once it's done
+	pageData := map[string]interface{}{
+		"AsyncLoad": true, // Trigger async loading in template
+		"ApiUrl":    h.basePath + "/api/users/" + userID + "/episodes",
+		"StatusUrl": h.basePath + "/api/users/" + userID + "/episodes/async",
+	}
+	if cached, found := h.cache.Get(fmt.Sprintf("episodes:%s", userID)); found {
+		if episodes, ok := cached.([]zepapi.Episode); ok {
+			pageData = map[string]interface{}{
+				"Episodes": episodes,
+			}
+		}
+	}
 	
-	// Create page data with breadcrumbs - load data asynchronously
+	// Create page data with breadcrumbs
 	data := map[string]interface{}{
 		"Title":    "User Episodes",
 		"SubTitle": "Episodes for user " + userID,
@@ -627,58 +752,54 @@ func (h *Handlers) UserEpisodes(w http.ResponseWriter, r *http.Request) {
 				Path:  r.URL.Path,
 			},
 		},
-		"Data": map[string]interface{}{
-			"AsyncLoad": true, // Trigger async loading in template
-			"ApiUrl":    h.basePath + "/api/users/" + userID + "/episodes",
-		},
+		"Data":      pageData,
 		"MenuItems": GetMenuItems(h.basePath),
 		"UserID":    userID,
 	}
 	
 	// Check if this is an HTMX request, if so render only the content
 	if r.Header.Get("HX-Request") == "true" {
-		if err := h.templates.ExecuteTemplate(w, "UserEpisodesContent", data); err != nil {
+		if err := h.render(w, r, "UserEpisodesContent", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	} else {
-		if err := h.templates.ExecuteTemplate(w, "Layout", data); err != nil {
+		if err := h.render(w, r, "Layout", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	}
 }
 
-// UserGraph handles the user graph visualization page with direct data loading
+// UserGraph handles the user graph visualization page. Cached graphs are
+// rendered directly; otherwise the page polls UserGraphAsync for progress.
 func (h *Handlers) UserGraph(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userId")
+	asOf, err := parseAsOf(r.URL.Query().Get("as_of"))
+	if err != nil {
+		log.Printf("⚠️ Ignoring as_of for graph of user %s: %v", userID, err)
+	}
+	showInvalid, err := parseShowInvalid(r.URL.Query().Get("invalid"))
+	if err != nil {
+		log.Printf("⚠️ Ignoring invalid for graph of user %s: %v", userID, err)
+	}
+	
+	graphData := map[string]interface{}{
+		"StreamUrl": h.basePath + "/api/users/" + userID + "/graph/stream",
+	}
 	
 	// Check cache first
 	cacheKey := fmt.Sprintf("graph:%s", userID)
-	var triplets []zepapi.RawTriplet
-	
 	if cached, found := h.cache.Get(cacheKey); found && cached != nil {
-		if cachedTriplets, ok := cached.([]zepapi.RawTriplet); ok {
-			triplets = cachedTriplets
-			log.Printf("📊 Cache hit for user graph: %s (%d triplets)", userID, len(triplets))
-		}
-	}
-	
-	// If not cached, fetch from API
-	if triplets == nil {
-		var err error
-		triplets, err = h.apiClient.GetUserGraphTriplets(userID)
-		if err != nil {
-			log.Printf("❌ Failed to get graph triplets for user %s: %v", userID, err)
-			triplets = []zepapi.RawTriplet{} // Empty slice for template
-		} else {
-			// Cache the result
-			h.cache.Set(cacheKey, triplets, 5*time.Minute)
-			log.Printf("✅ Loaded %d triplets for user graph: %s", len(triplets), userID)
+		if graph, ok := cached.(*zepapi.Graph); ok {
+			log.Printf("📊 Cache hit for user graph: %s (%d nodes, %d edges)", userID, len(graph.Nodes), len(graph.Edges))
+			graphData = map[string]interface{}{
+				"Graph": graph,
+			}
 		}
 	}
 	
-	// Create page data with breadcrumbs and actual graph data
+	// Create page data with breadcrumbs and graph data
 	data := map[string]interface{}{
 		"Title":    "User Graph",
 		"SubTitle": "Knowledge graph visualization for user " + userID,
@@ -698,21 +819,29 @@ func (h *Handlers) UserGraph(w http.ResponseWriter, r *http.Request) {
 				Path:  r.URL.Path,
 			},
 		},
-		"Data": map[string]interface{}{
-			"Triplets": triplets, // Provide actual triplets data
-		},
+		"Data":      graphData,
 		"MenuItems": GetMenuItems(h.basePath),
 		"UserID":    userID,
+		// Node to centre and highlight once the graph is drawn
+		"Focus":         r.URL.Query().Get("focus"),
+		"SearchPath":    r.URL.Path + "/search",
+		"ExportPath":    r.URL.Path + "/export",
+		"NodesPath":     r.URL.Path + "/nodes/",
+		"ExportFormats": graphExportLinks,
+		// Starting point of the time slider, from ?as_of=; invalidated facts
+		// are greyed out unless ?invalid=hide
+		"AsOf":        formatAsOf(asOf),
+		"ShowInvalid": showInvalid,
 	}
 	
 	// Check if this is an HTMX request, if so render only the content
 	if r.Header.Get("HX-Request") == "true" {
-		if err := h.templates.ExecuteTemplate(w, "UserGraphContent", data); err != nil {
+		if err := h.render(w, r, "UserGraphContent", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	} else {
-		if err := h.templates.ExecuteTemplate(w, "Layout", data); err != nil {
+		if err := h.render(w, r, "Layout", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -722,9 +851,11 @@ func (h *Handlers) UserGraph(w http.ResponseWriter, r *http.Request) {
 // UpdateUser handles user detail form submissions
 func (h *Handlers) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userId")
+	entry := h.auditEntry(r, "user.update", "user", userID)
 	
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
+		h.recordAudit(entry, err)
 		http.Error(w, "Failed to parse form data", http.StatusBadRequest)
 		return
 	}
@@ -734,87 +865,85 @@ func (h *Handlers) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		"user_id": userID,
 	}
 	
-	if firstName := r.FormValue("first_name"); firstName != "" {
-		updateReq["first_name"] = firstName
+	changes, fieldErrs := userFieldChanges(r.Form)
+	if len(fieldErrs) > 0 {
+		h.recordAudit(entry, errors.New("invalid user fields"))
+		h.renderFieldErrors(w, fieldErrs)
+		return
+	}
+	
+	// Keep the previous values for the audit trail
+	before, beforeErr := h.apiClient.GetUser(userID)
+	if beforeErr == nil {
+		entry.Before = userSnapshot(before)
 	}
 	
-	if lastName := r.FormValue("last_name"); lastName != "" {
-		updateReq["last_name"] = lastName
+	// A field sent empty is cleared, so pass the empty value on as well
+	for name, value := range changes {
+		updateReq[name] = value
 	}
 	
-	if email := r.FormValue("email"); email != "" {
-		updateReq["email"] = email
+	// Metadata is sent as the full map the update should leave behind, so
+	// merging needs the current keys
+	var metadata map[string]interface{}
+	if _, ok := r.Form["metadata"]; ok {
+		edited, err := parseMetadata(r.FormValue("metadata"))
+		if err != nil {
+			h.recordAudit(entry, err)
+			h.renderFieldErrors(w, map[string]string{metadataErrorField: err.Error()})
+			return
+		}
+		mode, err := parseMetadataMode(r.FormValue("metadata_mode"))
+		if err != nil {
+			h.recordAudit(entry, err)
+			h.renderFieldErrors(w, map[string]string{metadataErrorField: err.Error()})
+			return
+		}
+		if beforeErr != nil {
+			h.recordAudit(entry, beforeErr)
+			h.renderFieldErrors(w, map[string]string{formErrorField: "Failed to load the current metadata: " + beforeErr.Error()})
+			return
+		}
+		metadata = resolveMetadata(before.Metadata, edited, mode)
+		updateReq["metadata"] = metadata
 	}
 	
 	// Update user via API
-	_, err := h.apiClient.UpdateUser(userID, updateReq)
+	updated, err := h.apiClient.UpdateUser(userID, updateReq)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("❌ Failed to update user %s: %v", userID, err)
+		h.recordAudit(entry, err)
+		h.renderFieldErrors(w, map[string]string{formErrorField: "Failed to save: " + err.Error()})
 		return
 	}
+	entry.After = userSnapshot(updated)
+	h.invalidateUserList()
 	
-	// For HTMX requests, redirect to refresh the page
-	if r.Header.Get("HX-Request") == "true" {
-		w.Header().Set("HX-Redirect", r.URL.Path)
-		w.WriteHeader(http.StatusOK)
-	} else {
-		// For regular requests, redirect to user details page
-		http.Redirect(w, r, r.URL.Path, http.StatusFound)
-	}
-}
-
-// DeleteUser handles user deletion with async processing
-func (h *Handlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
-	userID := chi.URLParam(r, "userId")
-	
-	log.Printf("🗑️ Starting user deletion for: %s", userID)
-	
-	// Perform actual deletion and wait for completion
-	err := h.apiClient.DeleteUserWithCleanup(userID)
-	if err != nil {
-		log.Printf("❌ User deletion failed for %s: %v", userID, err)
-		
-		// Return JSON error response for HTMX requests
-		if r.Header.Get("HX-Request") == "true" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"error":   "User deletion failed",
-				"message": err.Error(),
-				"confirmed": false,
-			})
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Zep may ignore an empty value, in which case the clear did not happen,
+	// and may merge metadata on its side, in which case removed keys survive.
+	// Either way the rest of the update is saved.
+	warnings := keptUserFields(changes, updated)
+	if metadata != nil {
+		if kept := removedKeysKept(before.Metadata, metadata, updated.Metadata); len(kept) > 0 {
+			warnings[metadataErrorField] = fmt.Sprintf("Saved, but the Zep server kept %s. It merges metadata, so keys cannot be removed from here.", strings.Join(kept, ", "))
 		}
+	}
+	if len(warnings) > 0 {
+		log.Printf("⚠️ Zep did not apply the whole update to user %s", userID)
+		entry.Outcome = audit.OutcomePartial
+		h.recordAudit(entry, errors.New(joinWarnings(warnings)))
+		h.renderFieldWarnings(w, warnings)
 		return
 	}
+	h.recordAudit(entry, nil)
 	
-	log.Printf("✅ User deletion completed for: %s", userID)
-	
-	// Clear any cached user data
-	h.cache.Delete(fmt.Sprintf("user:%s", userID))
-	h.cache.Delete(fmt.Sprintf("episodes:%s", userID))
-	h.cache.Delete(fmt.Sprintf("graph:%s", userID))
-	
-	// For HTMX requests, return JSON confirmation with redirect header
+	// For HTMX requests, redirect to refresh the page
 	if r.Header.Get("HX-Request") == "true" {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("HX-Redirect", h.basePath+"/users")
+		w.Header().Set("HX-Redirect", r.URL.Path)
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "User deleted successfully",
-			"confirmed": true,
-			"user_id": userID,
-			"deleted_resources": map[string]interface{}{
-				"user":     userID,
-				"sessions": "all user sessions deleted",
-				"memories": "all session memories deleted",
-				"messages": "all session messages deleted",
-			},
-		})
 	} else {
-		// For regular requests, redirect to users list
-		http.Redirect(w, r, h.basePath+"/users", http.StatusFound)
+		// For regular requests, redirect to user details page
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
 	}
 }
 
@@ -841,12 +970,12 @@ func (h *Handlers) CreateUserForm(w http.ResponseWriter, r *http.Request) {
 	
 	// Check if this is an HTMX request
 	if r.Header.Get("HX-Request") == "true" {
-		if err := h.templates.ExecuteTemplate(w, "CreateUserContent", data); err != nil {
+		if err := h.render(w, r, "CreateUserContent", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	} else {
-		if err := h.templates.ExecuteTemplate(w, "Layout", data); err != nil {
+		if err := h.render(w, r, "Layout", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -856,33 +985,53 @@ func (h *Handlers) CreateUserForm(w http.ResponseWriter, r *http.Request) {
 // CreateUser handles creating a new user
 func (h *Handlers) CreateUser(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
+		h.recordAudit(h.auditEntry(r, "user.create", "user", ""), err)
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
 		return
 	}
 
 	userID := r.FormValue("user_id")
+	entry := h.auditEntry(r, "user.create", "user", userID)
 	if userID == "" {
-		http.Error(w, "User ID is required", http.StatusBadRequest)
+		h.recordAudit(entry, errors.New("user ID is required"))
+		h.renderFieldErrors(w, map[string]string{formErrorField: "User ID is required"})
+		return
+	}
+
+	changes, fieldErrs := userFieldChanges(r.Form)
+	if len(fieldErrs) > 0 {
+		h.recordAudit(entry, errors.New("invalid user fields"))
+		h.renderFieldErrors(w, fieldErrs)
+		return
+	}
+
+	metadata, err := parseMetadata(r.FormValue("metadata"))
+	if err != nil {
+		h.recordAudit(entry, err)
+		h.renderFieldErrors(w, map[string]string{metadataErrorField: err.Error()})
 		return
 	}
 
 	// Create user via API
 	createReq := map[string]interface{}{
 		"user_id":    userID,
-		"email":      r.FormValue("email"),
-		"first_name": r.FormValue("first_name"),
-		"last_name":  r.FormValue("last_name"),
-		"metadata":   map[string]interface{}{},
+		"email":      changes["email"],
+		"first_name": changes["first_name"],
+		"last_name":  changes["last_name"],
+		"metadata":   metadata,
 	}
 
-	_, err := h.apiClient.CreateUser(createReq)
+	created, err := h.apiClient.CreateUser(createReq)
+	entry.After = userSnapshot(created)
+	h.recordAudit(entry, err)
 	if err != nil {
 		log.Printf("❌ Create user error: %v", err)
-		http.Error(w, fmt.Sprintf("Failed to create user: %v", err), http.StatusInternalServerError)
+		h.renderFieldErrors(w, map[string]string{formErrorField: "Failed to create user: " + err.Error()})
 		return
 	}
 
 	log.Printf("✅ Successfully created user: %s", userID)
+	h.invalidateUserList()
 	
 	// Redirect to users list
 	http.Redirect(w, r, h.basePath+"/users", http.StatusSeeOther)
@@ -1044,12 +1193,12 @@ func (h *Handlers) Settings(w http.ResponseWriter, r *http.Request) {
 	
 	// Check if this is an HTMX request, if so render only the content
 	if r.Header.Get("HX-Request") == "true" {
-		if err := h.templates.ExecuteTemplate(w, "SettingsContent", data); err != nil {
+		if err := h.render(w, r, "SettingsContent", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	} else {
-		if err := h.templates.ExecuteTemplate(w, "Layout", data); err != nil {
+		if err := h.render(w, r, "Layout", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -1058,124 +1207,47 @@ func (h *Handlers) Settings(w http.ResponseWriter, r *http.Request) {
 
 // API handlers for HTMX requests
 func (h *Handlers) SessionListAPI(w http.ResponseWriter, r *http.Request) {
-	sessions, err := h.apiClient.GetSessions()
+	query := parseTableQuery(r, SessionTableColumns)
+
+	tableData, err := h.sessionTableData(query)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	// Parse query parameters for sorting and pagination
-	currentPage := 1
-	pageSize := 10
-	orderBy := "created_at"
-	asc := false
-
-	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
-		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
-			currentPage = page
-		}
-	}
-
-	if order := r.URL.Query().Get("order"); order != "" {
-		orderBy = order
-	}
-
-	if ascStr := r.URL.Query().Get("asc"); ascStr == "true" {
-		asc = true
-	}
-
-	// Convert sessions to SessionRows for template compatibility
-	sessionRows := make([]SessionRow, len(sessions))
-	for i := range sessions {
-		sessionRows[i] = SessionRow{Session: &sessions[i]}
-	}
-
-	// Calculate pagination
-	totalCount := len(sessions)
-	pageCount := (totalCount + pageSize - 1) / pageSize
-	rowCount := len(sessionRows)
-
-	// Create table data structure
-	tableData := &TableData{
-		TableID:     "session-table",
-		Columns:     SessionTableColumns,
-		Rows:        sessionRows,
-		TotalCount:  totalCount,
-		RowCount:    rowCount,
-		CurrentPage: currentPage,
-		PageSize:    pageSize,
-		PageCount:   pageCount,
-		OrderBy:     orderBy,
-		Asc:         asc,
-	}
+	tableData.Selectable = h.permissions(r).CanDelete
 
 	// Create page data for HTMX response
 	pageData := &PageData{
-		Path:      r.URL.Path,
+		Path:      tablePath(r, query),
 		Data:      tableData,
 		MenuItems: GetMenuItems(h.basePath),
 	}
 	
-	if err := h.templates.ExecuteTemplate(w, "SessionTable", pageData); err != nil {
+	if err := h.render(w, r, "SessionTable", pageData); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
 func (h *Handlers) UserListAPI(w http.ResponseWriter, r *http.Request) {
-	users, err := h.apiClient.GetUsersWithSessionCounts()
+	query := parseTableQuery(r, UserTableColumns)
+
+	tableData, err := h.userTableData(query)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	// Parse query parameters for sorting and pagination
-	currentPage := 1
-	pageSize := 10
-	orderBy := "created_at"
-	asc := false
-
-	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
-		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
-			currentPage = page
-		}
-	}
-
-	if order := r.URL.Query().Get("order"); order != "" {
-		orderBy = order
-	}
-
-	if ascStr := r.URL.Query().Get("asc"); ascStr == "true" {
-		asc = true
-	}
-
-	// Calculate pagination
-	totalCount := len(users)
-	pageCount := (totalCount + pageSize - 1) / pageSize
-	rowCount := len(users)
-
-	// Create table data structure
-	tableData := &TableData{
-		TableID:     "user-table",
-		Columns:     UserTableColumns,
-		Rows:        users, // Users slice directly
-		TotalCount:  totalCount,
-		RowCount:    rowCount,
-		CurrentPage: currentPage,
-		PageSize:    pageSize,
-		PageCount:   pageCount,
-		OrderBy:     orderBy,
-		Asc:         asc,
-	}
+	tableData.Selectable = h.permissions(r).CanDelete
 
 	// Create page data for HTMX response
 	pageData := &PageData{
-		Path:      r.URL.Path,
+		Path:      tablePath(r, query),
 		Data:      tableData,
+		Search:    query.Search,
 		MenuItems: GetMenuItems(h.basePath),
 	}
 	
-	if err := h.templates.ExecuteTemplate(w, "UserTable", pageData); err != nil {
+	if err := h.render(w, r, "ModernUserTableRows", pageData); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -1198,7 +1270,7 @@ func (h *Handlers) UserEpisodesAPI(w http.ResponseWriter, r *http.Request) {
 				},
 			}
 			
-			if err := h.templates.ExecuteTemplate(w, "UserEpisodesContent", data); err != nil {
+			if err := h.render(w, r, "UserEpisodesContent", data); err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
@@ -1219,7 +1291,7 @@ func (h *Handlers) UserEpisodesAPI(w http.ResponseWriter, r *http.Request) {
 			},
 		}
 		
-		if err := h.templates.ExecuteTemplate(w, "UserEpisodesContent", data); err != nil {
+		if err := h.render(w, r, "UserEpisodesContent", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -1238,73 +1310,69 @@ func (h *Handlers) UserEpisodesAPI(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 	
-	if err := h.templates.ExecuteTemplate(w, "UserEpisodesContent", data); err != nil {
+	if err := h.render(w, r, "UserEpisodesContent", data); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
-// UserGraphAPI handles the API endpoint for user graph data (for async loading)
+// UserGraphAPI handles the API endpoint for user graph data (for async loading).
+// With ?as_of= only the facts that held at that instant are returned, plus
+// the ones invalidated by then unless ?invalid=hide, for the page to grey out.
 func (h *Handlers) UserGraphAPI(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userId")
-	
-	// Check cache first
-	cacheKey := fmt.Sprintf("graph:%s", userID)
-	if cached, found := h.cache.Get(cacheKey); found && cached != nil {
-		if triplets, ok := cached.([]zepapi.RawTriplet); ok {
-			log.Printf("📊 Cache hit for user graph: %s (%d triplets)", userID, len(triplets))
-			
-			data := map[string]interface{}{
-				"UserID": userID,
-				"Data": map[string]interface{}{
-					"Triplets": triplets,
-				},
-			}
-			
-			if err := h.templates.ExecuteTemplate(w, "UserGraphContent", data); err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			return
-		}
+
+	asOf, err := parseAsOf(r.URL.Query().Get("as_of"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	
-	// Fetch graph triplets from API
-	triplets, err := h.apiClient.GetUserGraphTriplets(userID)
+	showInvalid, err := parseShowInvalid(r.URL.Query().Get("invalid"))
 	if err != nil {
-		log.Printf("❌ Failed to get graph triplets for user %s: %v", userID, err)
-		
-		// Return empty graph state
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	render := func(graph *zepapi.Graph) {
 		data := map[string]interface{}{
 			"UserID": userID,
 			"Data": map[string]interface{}{
-				"Triplets": []zepapi.RawTriplet{},
+				"Graph": filterGraphAsOf(graph, asOf, showInvalid),
 			},
+			"AsOf":        formatAsOf(asOf),
+			"ShowInvalid": showInvalid,
 		}
-		
-		if err := h.templates.ExecuteTemplate(w, "UserGraphContent", data); err != nil {
+
+		if err := h.render(w, r, "UserGraphContent", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		return
 	}
 	
-	// Cache the result
-	h.cache.Set(cacheKey, triplets, 5*time.Minute)
-	log.Printf("✅ Loaded %d triplets for user graph: %s", userID, len(triplets))
-	
-	// Create template data
-	data := map[string]interface{}{
-		"UserID": userID,
-		"Data": map[string]interface{}{
-			"Triplets": triplets,
-		},
+	// Check cache first
+	cacheKey := fmt.Sprintf("graph:%s", userID)
+	if cached, found := h.cache.Get(cacheKey); found && cached != nil {
+		if graph, ok := cached.(*zepapi.Graph); ok {
+			log.Printf("📊 Cache hit for user graph: %s (%d nodes, %d edges)", userID, len(graph.Nodes), len(graph.Edges))
+			render(graph)
+			return
+		}
 	}
 	
-	if err := h.templates.ExecuteTemplate(w, "UserGraphContent", data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Fetch graph from API
+	graph, err := h.apiClient.GetUserGraph(userID)
+	if err != nil {
+		log.Printf("❌ Failed to get graph for user %s: %v", userID, err)
+		
+		// Return empty graph state
+		render(&zepapi.Graph{})
 		return
 	}
+	
+	// Cache the result
+	h.cache.Set(cacheKey, graph, graphCacheTTL(graph, 5*time.Minute))
+	log.Printf("✅ Loaded %d nodes and %d edges for user graph: %s", len(graph.Nodes), len(graph.Edges), userID)
+	
+	render(graph)
 }
 
 // Logs handlers the centralized logs page
@@ -1326,12 +1394,12 @@ func (h *Handlers) Logs(w http.ResponseWriter, r *http.Request) {
 
 	// Check if this is an HTMX request, if so render only the content
 	if r.Header.Get("HX-Request") == "true" {
-		if err := h.templates.ExecuteTemplate(w, "LogsContent", data); err != nil {
+		if err := h.render(w, r, "LogsContent", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	} else {
-		if err := h.templates.ExecuteTemplate(w, "Layout", data); err != nil {
+		if err := h.render(w, r, "Layout", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+func TestNewTableData(t *testing.T) {
+	tests := []struct {
+		name             string
+		page, rowCount   int
+		totalCount       int
+		wantTotal        int
+		wantPages        int
+		wantTotalUnknown bool
+	}{
+		{name: "known total", page: 1, rowCount: 10, totalCount: 25, wantTotal: 25, wantPages: 3},
+		{name: "empty", page: 1, rowCount: 0, totalCount: 0, wantTotal: 0, wantPages: 1},
+		{name: "unknown total, full first page", page: 1, rowCount: 10, totalCount: zepapi.UnknownTotal, wantTotal: 10, wantPages: 2, wantTotalUnknown: true},
+		{name: "unknown total, full later page", page: 3, rowCount: 10, totalCount: zepapi.UnknownTotal, wantTotal: 30, wantPages: 4, wantTotalUnknown: true},
+		{name: "unknown total, short page is the last", page: 3, rowCount: 4, totalCount: zepapi.UnknownTotal, wantTotal: 24, wantPages: 3},
+		{name: "unknown total, nothing at all", page: 1, rowCount: 0, totalCount: zepapi.UnknownTotal, wantTotal: 0, wantPages: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tableQuery{Page: tt.page, PageSize: 10, OrderBy: "created_at"}
+			got := newTableData("users", nil, nil, tt.rowCount, tt.totalCount, query)
+			if got.TotalCount != tt.wantTotal {
+				t.Errorf("TotalCount = %d, want %d", got.TotalCount, tt.wantTotal)
+			}
+			if got.PageCount != tt.wantPages {
+				t.Errorf("PageCount = %d, want %d", got.PageCount, tt.wantPages)
+			}
+			if got.TotalUnknown != tt.wantTotalUnknown {
+				t.Errorf("TotalUnknown = %v, want %v", got.TotalUnknown, tt.wantTotalUnknown)
+			}
+			if got.CurrentPage != tt.page {
+				t.Errorf("CurrentPage = %d, want %d", got.CurrentPage, tt.page)
+			}
+		})
+	}
+}
@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+// Limits on an uploaded import file
+const (
+	maxImportSize = 10 << 20
+	maxImportRows = 10000
+)
+
+// Import modes: create only new users, or also update the ones that exist
+const (
+	importCreate = "create"
+	importUpsert = "upsert"
+)
+
+// importColumns are the CSV columns and JSONL keys an import understands
+var importColumns = []string{"user_id", "email", "first_name", "last_name", "metadata"}
+
+// importRow is one user read from an import file and what importing it will do
+type importRow struct {
+	Line      int
+	UserID    string
+	Email     string
+	FirstName string
+	LastName  string
+	Metadata  map[string]interface{}
+	Action    string // "create", "update", "skip" or "invalid"
+	Error     string
+}
+
+// importPlan is the outcome of a dry run over an import file
+type importPlan struct {
+	Mode    string
+	Rows    []importRow
+	Create  int
+	Update  int
+	Skip    int
+	Invalid int
+
+	existing map[string]zepapi.User
+}
+
+// parseImportFile reads users from CSV, which needs a header row, or from
+// JSONL with one object per line. The format follows the file extension.
+func parseImportFile(filename string, r io.Reader) ([]importRow, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return parseImportCSV(r)
+	case ".jsonl", ".ndjson":
+		return parseImportJSONL(r)
+	default:
+		return nil, errors.New("upload a .csv or .jsonl file")
+	}
+}
+
+func parseImportCSV(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !isImportColumn(name) {
+			return nil, fmt.Errorf("unknown column %q; expected %s", name, strings.Join(importColumns, ", "))
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["user_id"]; !ok {
+		return nil, errors.New("the header must include a user_id column")
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("the file has more than %d rows", maxImportRows)
+		}
+
+		value := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row := importRow{
+			Line:      line,
+			UserID:    value("user_id"),
+			Email:     value("email"),
+			FirstName: value("first_name"),
+			LastName:  value("last_name"),
+		}
+		if raw := value("metadata"); raw != "" {
+			if row.Metadata, err = parseMetadata(raw); err != nil {
+				row.Error = err.Error()
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseImportJSONL(r io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportSize)
+
+	var rows []importRow
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("the file has more than %d rows", maxImportRows)
+		}
+
+		row := importRow{Line: line}
+		var record map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			row.Error = "not a JSON object"
+			rows = append(rows, row)
+			continue
+		}
+		for key, raw := range record {
+			if !isImportColumn(key) {
+				row.Error = fmt.Sprintf("unknown key %q", key)
+				continue
+			}
+			if key == "metadata" {
+				if string(raw) != "null" {
+					metadata, err := parseMetadata(string(raw))
+					if err != nil {
+						row.Error = err.Error()
+					}
+					row.Metadata = metadata
+				}
+				continue
+			}
+			var value string
+			if string(raw) != "null" && json.Unmarshal(raw, &value) != nil {
+				row.Error = key + " must be a string"
+				continue
+			}
+			switch key {
+			case "user_id":
+				row.UserID = strings.TrimSpace(value)
+			case "email":
+				row.Email = strings.TrimSpace(value)
+			case "first_name":
+				row.FirstName = strings.TrimSpace(value)
+			case "last_name":
+				row.LastName = strings.TrimSpace(value)
+			}
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the file: %v", err)
+	}
+	return rows, nil
+}
+
+func isImportColumn(name string) bool {
+	for _, column := range importColumns {
+		if name == column {
+			return true
+		}
+	}
+	return false
+}
+
+// planImport validates every row and decides what importing it will do,
+// given the users that already exist
+func planImport(rows []importRow, existing []zepapi.User, mode string) *importPlan {
+	plan := &importPlan{Mode: mode, existing: make(map[string]zepapi.User, len(existing))}
+	for _, user := range existing {
+		plan.existing[user.UserID] = user
+	}
+
+	seen := make(map[string]int)
+	for _, row := range rows {
+		if row.Error == "" {
+			row.Error = validateImportRow(row)
+		}
+		if row.Error == "" {
+			if first, ok := seen[row.UserID]; ok {
+				row.Error = fmt.Sprintf("user_id repeats line %d", first)
+			} else {
+				seen[row.UserID] = row.Line
+			}
+		}
+
+		_, exists := plan.existing[row.UserID]
+		switch {
+		case row.Error != "":
+			row.Action = "invalid"
+			plan.Invalid++
+		case exists && mode == importUpsert:
+			row.Action = "update"
+			plan.Update++
+		case exists:
+			row.Action = "skip"
+			row.Error = "user already exists"
+			plan.Skip++
+		default:
+			row.Action = "create"
+			plan.Create++
+		}
+		plan.Rows = append(plan.Rows, row)
+	}
+	return plan
+}
+
+func validateImportRow(row importRow) string {
+	if row.UserID == "" {
+		return "user_id is required"
+	}
+	values := map[string]string{
+		"first_name": row.FirstName,
+		"last_name":  row.LastName,
+		"email":      row.Email,
+	}
+	for _, field := range userFields {
+		if msg := validateUserField(field, values[field.Name]); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+// loadImportPlan reads the uploaded file and dry-runs it against the
+// current users
+func (h *Handlers) loadImportPlan(w http.ResponseWriter, r *http.Request) (*importPlan, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize+1<<20)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		return nil, fmt.Errorf("failed to read the upload (the limit is %d MB)", maxImportSize>>20)
+	}
+
+	mode := r.FormValue("mode")
+	if mode != importCreate && mode != importUpsert {
+		return nil, fmt.Errorf("mode must be %s or %s", importCreate, importUpsert)
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, errors.New("choose a file to import")
+	}
+	defer file.Close()
+
+	rows, err := parseImportFile(header.Filename, file)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("the file has no users")
+	}
+
+	existing, err := h.apiClient.GetUsers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing users: %v", err)
+	}
+	return planImport(rows, existing, mode), nil
+}
+
+// ImportUsersPage renders the bulk import form
+func (h *Handlers) ImportUsersPage(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{
+		"Title":    "Import Users",
+		"SubTitle": "Create or update users from a CSV or JSONL file",
+		"Page":     "import_users",
+		"Path":     r.URL.Path,
+		"BreadCrumbs": []BreadCrumb{
+			{
+				Title: "Users",
+				Path:  h.basePath + "/users",
+			},
+			{
+				Title: "Import Users",
+				Path:  r.URL.Path,
+			},
+		},
+		"Columns":   importColumns,
+		"MaxRows":   maxImportRows,
+		"MenuItems": GetMenuItems(h.basePath),
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		if err := h.render(w, r, "ImportUsersContent", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		if err := h.render(w, r, "Layout", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// ImportUsersPreview dry-runs an import file and shows what each row will
+// do, without changing anything
+func (h *Handlers) ImportUsersPreview(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{}
+	if plan, err := h.loadImportPlan(w, r); err != nil {
+		data["Error"] = err.Error()
+	} else {
+		data["Plan"] = plan
+	}
+
+	if err := h.render(w, r, "UserImportPreview", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ImportUsers dry-runs the file again and imports it as a background job.
+// Files with invalid rows are refused so a fixed file can be imported whole.
+func (h *Handlers) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	plan, err := h.loadImportPlan(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if plan.Invalid > 0 {
+		http.Error(w, fmt.Sprintf("Fix the %d invalid rows and upload the file again", plan.Invalid), http.StatusBadRequest)
+		return
+	}
+
+	job := jobTracker.StartJob("user_import", fmt.Sprintf("Import %d users", len(plan.Rows)), BreadCrumb{
+		Title: "Users",
+		Path:  h.basePath + "/users",
+	}, len(plan.Rows))
+	log.Printf("📥 Starting import job %s for %d users (%s)", job.ID, len(plan.Rows), plan.Mode)
+
+	createEntry := h.auditEntry(r, "user.create", "user", "")
+	createEntry.Details = map[string]string{"job_id": job.ID}
+	updateEntry := h.auditEntry(r, "user.update", "user", "")
+	updateEntry.Details = map[string]string{"job_id": job.ID}
+
+	go func() {
+		for _, row := range plan.Rows {
+			result := JobResult{ID: fmt.Sprintf("line %d: %s", row.Line, row.UserID), Status: "succeeded"}
+			var err error
+			switch row.Action {
+			case "create":
+				entry := createEntry
+				entry.TargetID = row.UserID
+				var created *zepapi.User
+				created, err = h.apiClient.CreateUser(importCreateRequest(row))
+				entry.After = userSnapshot(created)
+				h.recordAudit(entry, err)
+			case "update":
+				current := plan.existing[row.UserID]
+				entry := updateEntry
+				entry.TargetID = row.UserID
+				entry.Before = userSnapshot(&current)
+				var updated *zepapi.User
+				updated, err = h.apiClient.UpdateUser(row.UserID, importUpdateRequest(row, current))
+				entry.After = userSnapshot(updated)
+				h.recordAudit(entry, err)
+			default:
+				result.Status = "skipped"
+				result.Error = row.Error
+			}
+			if err != nil {
+				result.Status = "failed"
+				result.Error = err.Error()
+			}
+			jobTracker.AddResult(job.ID, result)
+		}
+		h.invalidateUserList()
+		jobTracker.Finish(job.ID, nil)
+	}()
+
+	// HTMX follows the redirect to the job view with a full page load
+	jobURL := h.basePath + "/jobs/" + job.ID
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", jobURL)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	http.Redirect(w, r, jobURL, http.StatusSeeOther)
+}
+
+func importCreateRequest(row importRow) map[string]interface{} {
+	metadata := row.Metadata
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"user_id":    row.UserID,
+		"email":      row.Email,
+		"first_name": row.FirstName,
+		"last_name":  row.LastName,
+		"metadata":   metadata,
+	}
+}
+
+// importUpdateRequest only changes the values a row provides: empty values
+// are left alone and metadata keys are merged into the existing ones
+func importUpdateRequest(row importRow, current zepapi.User) map[string]interface{} {
+	req := map[string]interface{}{"user_id": row.UserID}
+	if row.Email != "" {
+		req["email"] = row.Email
+	}
+	if row.FirstName != "" {
+		req["first_name"] = row.FirstName
+	}
+	if row.LastName != "" {
+		req["last_name"] = row.LastName
+	}
+	if row.Metadata != nil {
+		req["metadata"] = resolveMetadata(current.Metadata, row.Metadata, metadataMerge)
+	}
+	return req
+}
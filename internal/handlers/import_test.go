@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    []importRow
+		wantErr string // empty when the file must parse
+	}{
+		{
+			name: "all columns",
+			file: "user_id,email,first_name,last_name,metadata\n" +
+				"ann, ann@example.com ,Ann,Lee,\"{\"\"tier\"\": \"\"gold\"\"}\"\n",
+			want: []importRow{{Line: 2, UserID: "ann", Email: "ann@example.com", FirstName: "Ann", LastName: "Lee", Metadata: map[string]interface{}{"tier": "gold"}}},
+		},
+		{
+			name: "header with byte order mark, case and reordering",
+			file: "\ufeffEmail,USER_ID\nbob@example.com,bob\n",
+			want: []importRow{{Line: 2, UserID: "bob", Email: "bob@example.com"}},
+		},
+		{
+			name: "short record",
+			file: "user_id,email,first_name\ncid\n",
+			want: []importRow{{Line: 2, UserID: "cid"}},
+		},
+		{
+			name: "quoted field across lines keeps the line it starts on",
+			file: "user_id,last_name\n\"dee\",\"Two\nLines\"\neve,Ng\n",
+			want: []importRow{{Line: 2, UserID: "dee", LastName: "Two\nLines"}, {Line: 4, UserID: "eve", LastName: "Ng"}},
+		},
+		{
+			name: "bad metadata marks the row",
+			file: "user_id,metadata\nfay,[1]\n",
+			want: []importRow{{Line: 2, UserID: "fay", Error: "metadata must be a JSON object"}},
+		},
+		{name: "empty file", file: "", wantErr: "the file is empty"},
+		{name: "unknown column", file: "user_id,nickname\n", wantErr: `unknown column "nickname"`},
+		{name: "no user_id column", file: "email\nann@example.com\n", wantErr: "must include a user_id column"},
+		{name: "broken quoting", file: "user_id\n\"ann\n", wantErr: "invalid CSV"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseImportCSV(strings.NewReader(tt.file))
+			checkImportRows(t, got, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestParseImportJSONL(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    []importRow
+		wantErr string
+	}{
+		{
+			name: "all keys",
+			file: `{"user_id": " ann ", "email": "ann@example.com", "first_name": "Ann", "last_name": "Lee", "metadata": {"tier": "gold"}}` + "\n",
+			want: []importRow{{Line: 1, UserID: "ann", Email: "ann@example.com", FirstName: "Ann", LastName: "Lee", Metadata: map[string]interface{}{"tier": "gold"}}},
+		},
+		{
+			name: "blank lines are skipped but counted",
+			file: "\n{\"user_id\": \"bob\"}\n\n{\"user_id\": \"cid\", \"email\": null, \"metadata\": null}\n",
+			want: []importRow{{Line: 2, UserID: "bob"}, {Line: 4, UserID: "cid"}},
+		},
+		{
+			name: "not an object",
+			file: "[\"dee\"]\n",
+			want: []importRow{{Line: 1, Error: "not a JSON object"}},
+		},
+		{
+			name: "unknown key",
+			file: `{"user_id": "eve", "nickname": "E"}` + "\n",
+			want: []importRow{{Line: 1, UserID: "eve", Error: `unknown key "nickname"`}},
+		},
+		{
+			name: "field that is not a string",
+			file: `{"user_id": 42}` + "\n",
+			want: []importRow{{Line: 1, Error: "user_id must be a string"}},
+		},
+		{
+			name: "metadata that is not an object",
+			file: `{"user_id": "fay", "metadata": "gold"}` + "\n",
+			want: []importRow{{Line: 1, UserID: "fay", Error: "metadata must be a JSON object"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseImportJSONL(strings.NewReader(tt.file))
+			checkImportRows(t, got, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestParseImportFileChecksExtension(t *testing.T) {
+	if _, err := parseImportFile("users.xlsx", strings.NewReader("user_id\nann\n")); err == nil {
+		t.Error("parseImportFile accepted an .xlsx file")
+	}
+	rows, err := parseImportFile("USERS.NDJSON", strings.NewReader(`{"user_id": "ann"}`))
+	if err != nil || len(rows) != 1 {
+		t.Errorf("parseImportFile(.NDJSON) = %d rows, %v; want 1 row", len(rows), err)
+	}
+}
+
+// checkImportRows compares parsed rows with want. Row errors only need to
+// contain the wanted text.
+func checkImportRows(t *testing.T, got []importRow, err error, want []importRow, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("error = %v, want it to contain %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Line != w.Line || g.UserID != w.UserID || g.Email != w.Email || g.FirstName != w.FirstName || g.LastName != w.LastName {
+			t.Errorf("row %d = %+v, want %+v", i, g, w)
+		}
+		if !reflect.DeepEqual(g.Metadata, w.Metadata) {
+			t.Errorf("row %d metadata = %v, want %v", i, g.Metadata, w.Metadata)
+		}
+		if (w.Error == "") != (g.Error == "") || !strings.Contains(g.Error, w.Error) {
+			t.Errorf("row %d error = %q, want %q", i, g.Error, w.Error)
+		}
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	existing := []zepapi.User{{UserID: "ann"}, {UserID: "bob"}}
+	rows := []importRow{
+		{Line: 2, UserID: "ann", Email: "ann@example.com"},
+		{Line: 3, UserID: "cid"},
+		{Line: 4, UserID: "cid"},
+		{Line: 5, UserID: ""},
+		{Line: 6, UserID: "dee", Email: "not-an-address"},
+		{Line: 7, UserID: "eve", Error: "metadata is not valid JSON"},
+		{Line: 8, UserID: "bob"},
+	}
+
+	tests := []struct {
+		mode        string
+		wantActions []string
+		wantCounts  [4]int // create, update, skip, invalid
+	}{
+		{importCreate, []string{"skip", "create", "invalid", "invalid", "invalid", "invalid", "skip"}, [4]int{1, 0, 2, 4}},
+		{importUpsert, []string{"update", "create", "invalid", "invalid", "invalid", "invalid", "update"}, [4]int{1, 2, 0, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			plan := planImport(rows, existing, tt.mode)
+			for i, row := range plan.Rows {
+				if row.Action != tt.wantActions[i] {
+					t.Errorf("line %d action = %q (%s), want %q", row.Line, row.Action, row.Error, tt.wantActions[i])
+				}
+			}
+			if got := [4]int{plan.Create, plan.Update, plan.Skip, plan.Invalid}; got != tt.wantCounts {
+				t.Errorf("counts = %v, want %v", got, tt.wantCounts)
+			}
+			if got := plan.Rows[2].Error; got != "user_id repeats line 3" {
+				t.Errorf("repeated user_id error = %q", got)
+			}
+			if got := plan.Rows[5].Error; got != "metadata is not valid JSON" {
+				t.Errorf("parse error = %q, want it kept", got)
+			}
+		})
+	}
+}
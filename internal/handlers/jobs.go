@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// JobResult is the outcome for a single item processed by a background job
+type JobResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"` // "succeeded", "partial", "failed", "skipped"
+	Error  string `json:"error,omitempty"`
+}
+
+// Job tracks a long-running background operation such as a bulk deletion
+type Job struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"`
+	Title      string      `json:"title"`
+	Status     string      `json:"status"`   // "running", "completed", "failed"
+	Progress   int         `json:"progress"` // 0-100
+	Message    string      `json:"message"`
+	Completed  int         `json:"completed"`
+	Total      int         `json:"total"`
+	Succeeded  int         `json:"succeeded"`
+	Failed     int         `json:"failed"`
+	Skipped    int         `json:"skipped,omitempty"`
+	Results    []JobResult `json:"results"`
+	Error      string      `json:"error,omitempty"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+
+	// DownloadURL is set once the job has produced a file to download
+	DownloadURL string `json:"download_url,omitempty"`
+
+	// Origin is the page the job was started from, for breadcrumbs
+	Origin BreadCrumb `json:"-"`
+
+	// artifact is the file behind DownloadURL, removed with the job
+	artifact     string
+	artifactName string
+}
+
+// Running reports whether the job is still in progress
+func (j *Job) Running() bool {
+	return j.Status == "running"
+}
+
+// JobTracker keeps background jobs in memory so their progress can be polled
+type JobTracker struct {
+	jobs  map[string]*Job
+	mutex sync.RWMutex
+}
+
+var jobTracker = &JobTracker{
+	jobs: make(map[string]*Job),
+}
+
+// jobRetention is how long finished jobs stay viewable
+const jobRetention = time.Hour
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// StartJob registers a new running job over total items
+func (jt *JobTracker) StartJob(kind, title string, origin BreadCrumb, total int) *Job {
+	jt.mutex.Lock()
+	defer jt.mutex.Unlock()
+
+	job := &Job{
+		ID:        newJobID(),
+		Kind:      kind,
+		Title:     title,
+		Status:    "running",
+		Message:   "Starting...",
+		Total:     total,
+		Results:   []JobResult{},
+		StartedAt: time.Now(),
+		Origin:    origin,
+	}
+	jt.jobs[job.ID] = job
+	return job
+}
+
+// AddResult records the outcome for one item and advances progress
+func (jt *JobTracker) AddResult(jobID string, result JobResult) {
+	jt.mutex.Lock()
+	defer jt.mutex.Unlock()
+
+	job, exists := jt.jobs[jobID]
+	if !exists {
+		return
+	}
+
+	job.Results = append(job.Results, result)
+	job.Completed++
+	// Partial outcomes left work behind, so they count as failures
+	switch result.Status {
+	case "succeeded":
+		job.Succeeded++
+	case "skipped":
+		job.Skipped++
+	default:
+		job.Failed++
+	}
+	if job.Total > 0 {
+		job.Progress = job.Completed * 100 / job.Total
+	}
+	job.Message = fmt.Sprintf("Processed %d of %d", job.Completed, job.Total)
+}
+
+// SetTotal updates the number of items once a job has discovered it
+func (jt *JobTracker) SetTotal(jobID string, total int) {
+	jt.mutex.Lock()
+	defer jt.mutex.Unlock()
+
+	if job, exists := jt.jobs[jobID]; exists {
+		job.Total = total
+	}
+}
+
+// SetArtifact attaches a file the job produced. name is the filename the
+// download is offered under.
+func (jt *JobTracker) SetArtifact(jobID, path, name, downloadURL string) {
+	jt.mutex.Lock()
+	defer jt.mutex.Unlock()
+
+	if job, exists := jt.jobs[jobID]; exists {
+		job.artifact = path
+		job.artifactName = name
+		job.DownloadURL = downloadURL
+	}
+}
+
+// Finish marks the job as done. A non-nil err marks the whole job as failed.
+func (jt *JobTracker) Finish(jobID string, err error) {
+	jt.mutex.Lock()
+	defer jt.mutex.Unlock()
+
+	job, exists := jt.jobs[jobID]
+	if !exists {
+		return
+	}
+
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = "failed"
+		job.Error = err.Error()
+		job.Message = "Job failed"
+	} else {
+		job.Status = "completed"
+		job.Progress = 100
+		job.Message = fmt.Sprintf("Completed: %d succeeded, %d failed", job.Succeeded, job.Failed)
+		if job.Skipped > 0 {
+			job.Message += fmt.Sprintf(", %d skipped", job.Skipped)
+		}
+	}
+
+	// Auto-cleanup once the job has been viewable for a while
+	go func() {
+		time.Sleep(jobRetention)
+		jt.mutex.Lock()
+		if job.artifact != "" {
+			os.Remove(job.artifact)
+		}
+		delete(jt.jobs, jobID)
+		jt.mutex.Unlock()
+	}()
+}
+
+// GetJob returns a snapshot of the job
+func (jt *JobTracker) GetJob(jobID string) (*Job, bool) {
+	jt.mutex.RLock()
+	defer jt.mutex.RUnlock()
+
+	job, exists := jt.jobs[jobID]
+	if !exists {
+		return nil, false
+	}
+
+	snapshot := *job
+	snapshot.Results = append([]JobResult(nil), job.Results...)
+	return &snapshot, true
+}
+
+// JobDetails renders the job view page
+func (h *Handlers) JobDetails(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobId")
+
+	job, exists := jobTracker.GetJob(jobID)
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":    job.Title,
+		"SubTitle": "Background job " + job.ID,
+		"Page":     "job",
+		"Path":     r.URL.Path,
+		"BreadCrumbs": []BreadCrumb{
+			job.Origin,
+			{
+				Title: job.Title,
+				Path:  r.URL.Path,
+			},
+		},
+		"Job":       job,
+		"StatusUrl": h.basePath + "/api/jobs/" + job.ID,
+		"MenuItems": GetMenuItems(h.basePath),
+	}
+
+	// Check if this is an HTMX request, if so render only the content
+	if r.Header.Get("HX-Request") == "true" {
+		if err := h.render(w, r, "JobContent", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		if err := h.render(w, r, "Layout", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// JobStatusAPI returns job progress: the JobStatus fragment for HTMX polling,
+// JSON otherwise
+func (h *Handlers) JobStatusAPI(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobId")
+
+	job, exists := jobTracker.GetJob(jobID)
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		data := map[string]interface{}{
+			"Job":       job,
+			"StatusUrl": h.basePath + "/api/jobs/" + job.ID,
+		}
+		if err := h.render(w, r, "JobStatus", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// JobDownload serves the file a finished job produced
+func (h *Handlers) JobDownload(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobId")
+
+	job, exists := jobTracker.GetJob(jobID)
+	if !exists || job.artifact == "" {
+		http.Error(w, "No download for this job", http.StatusNotFound)
+		return
+	}
+
+	file, err := os.Open(job.artifact)
+	if err != nil {
+		http.Error(w, "The download has expired", http.StatusGone)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="`+job.artifactName+`"`)
+	http.ServeContent(w, r, job.artifactName, info.ModTime(), file)
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxMetadataSize caps the metadata JSON accepted from a form
+const maxMetadataSize = 64 * 1024
+
+// Metadata modes for updates: merge edits into the existing keys, where a
+// null value removes a key, or replace the whole map
+const (
+	metadataMerge   = "merge"
+	metadataReplace = "replace"
+)
+
+// metadataChange is one top-level key in a metadata diff preview
+type metadataChange struct {
+	Key    string
+	Kind   string // "added", "removed" or "changed"
+	Before string
+	After  string
+}
+
+// parseMetadata decodes a form's metadata JSON, which must be an object.
+// Numbers are kept as written so large IDs survive the round trip.
+func parseMetadata(raw string) (map[string]interface{}, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return map[string]interface{}{}, nil
+	}
+	if len(raw) > maxMetadataSize {
+		return nil, fmt.Errorf("metadata is larger than %d KB", maxMetadataSize/1024)
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("metadata is not valid JSON: %v", err)
+	}
+	if decoder.More() {
+		return nil, errors.New("metadata must be a single JSON object")
+	}
+	metadata, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("metadata must be a JSON object, like {\"tenant_id\": \"acme\"}")
+	}
+	return metadata, nil
+}
+
+// parseMetadataMode reads metadata_mode, defaulting to merge
+func parseMetadataMode(mode string) (string, error) {
+	switch mode {
+	case "", metadataMerge:
+		return metadataMerge, nil
+	case metadataReplace:
+		return metadataReplace, nil
+	default:
+		return "", fmt.Errorf("metadata_mode must be %s or %s", metadataMerge, metadataReplace)
+	}
+}
+
+// resolveMetadata returns the full metadata map an update should leave behind
+func resolveMetadata(current, edited map[string]interface{}, mode string) map[string]interface{} {
+	if mode == metadataReplace {
+		return edited
+	}
+
+	result := make(map[string]interface{}, len(current)+len(edited))
+	for key, value := range current {
+		result[key] = value
+	}
+	for key, value := range edited {
+		if value == nil {
+			delete(result, key)
+		} else {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// diffMetadata lists the top-level keys that differ, sorted by key
+func diffMetadata(before, after map[string]interface{}) []metadataChange {
+	var changes []metadataChange
+	for key, value := range after {
+		previous, existed := before[key]
+		switch {
+		case !existed:
+			changes = append(changes, metadataChange{Key: key, Kind: "added", After: compactJSON(value)})
+		case !sameJSON(previous, value):
+			changes = append(changes, metadataChange{Key: key, Kind: "changed", Before: compactJSON(previous), After: compactJSON(value)})
+		}
+	}
+	for key, value := range before {
+		if _, kept := after[key]; !kept {
+			changes = append(changes, metadataChange{Key: key, Kind: "removed", Before: compactJSON(value)})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// removedKeysKept returns the keys an update meant to remove that the Zep
+// server still reports, which happens when it merges metadata itself
+func removedKeysKept(before, intended, saved map[string]interface{}) []string {
+	var kept []string
+	for key := range before {
+		if _, wanted := intended[key]; wanted {
+			continue
+		}
+		if _, still := saved[key]; still {
+			kept = append(kept, key)
+		}
+	}
+	sort.Strings(kept)
+	return kept
+}
+
+// sameJSON compares values by their JSON encoding, so a json.Number from a
+// form equals the float64 decoded from the Zep API
+func sameJSON(a, b interface{}) bool {
+	var left, right interface{}
+	if json.Unmarshal([]byte(compactJSON(a)), &left) != nil || json.Unmarshal([]byte(compactJSON(b)), &right) != nil {
+		return false
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func compactJSON(v interface{}) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// UserMetadataPreview renders the diff a metadata update would make,
+// without saving it
+func (h *Handlers) UserMetadataPreview(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userId")
+	data := map[string]interface{}{}
+
+	if err := r.ParseForm(); err != nil {
+		data["Error"] = "Failed to parse form"
+	} else if edited, err := parseMetadata(r.FormValue("metadata")); err != nil {
+		data["Error"] = err.Error()
+	} else if mode, err := parseMetadataMode(r.FormValue("metadata_mode")); err != nil {
+		data["Error"] = err.Error()
+	} else if user, err := h.apiClient.GetUser(userID); err != nil {
+		log.Printf("❌ Failed to load user %s for metadata preview: %v", userID, err)
+		data["Error"] = "Failed to load the current metadata: " + err.Error()
+	} else {
+		data["Mode"] = mode
+		data["Changes"] = diffMetadata(user.Metadata, resolveMetadata(user.Metadata, edited, mode))
+	}
+
+	if err := h.render(w, r, "UserMetadataPreview", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mustMetadata parses metadata the way a form submits it
+func mustMetadata(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	metadata, err := parseMetadata(raw)
+	if err != nil {
+		t.Fatalf("parseMetadata(%s): %v", raw, err)
+	}
+	return metadata
+}
+
+func TestParseMetadata(t *testing.T) {
+	tests := []struct {
+		raw     string
+		wantErr string // empty when the metadata must parse
+	}{
+		{raw: "", wantErr: ""},
+		{raw: `{"tier": "gold", "seats": 12345678901234567890}`, wantErr: ""},
+		{raw: `[1, 2]`, wantErr: "must be a JSON object"},
+		{raw: `"gold"`, wantErr: "must be a JSON object"},
+		{raw: `{"tier": }`, wantErr: "not valid JSON"},
+		{raw: `{"a": 1} {"b": 2}`, wantErr: "single JSON object"},
+		{raw: `{"blob": "` + strings.Repeat("x", maxMetadataSize) + `"}`, wantErr: "larger than"},
+	}
+	for _, tt := range tests {
+		_, err := parseMetadata(tt.raw)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("parseMetadata(%.40s): %v", tt.raw, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("parseMetadata(%.40s) error = %v, want it to contain %q", tt.raw, err, tt.wantErr)
+		}
+	}
+
+	// Large numbers keep every digit
+	metadata := mustMetadata(t, `{"seats": 12345678901234567890}`)
+	if got := compactJSON(metadata["seats"]); got != "12345678901234567890" {
+		t.Errorf("seats = %s, want 12345678901234567890", got)
+	}
+}
+
+func TestResolveMetadata(t *testing.T) {
+	current := map[string]interface{}{"tier": "silver", "region": "eu", "seats": 3.0}
+
+	tests := []struct {
+		name   string
+		edited string
+		mode   string
+		want   string
+	}{
+		{"merge adds and changes", `{"tier": "gold", "plan": "annual"}`, metadataMerge, `{"plan":"annual","region":"eu","seats":3,"tier":"gold"}`},
+		{"merge null removes", `{"region": null}`, metadataMerge, `{"seats":3,"tier":"silver"}`},
+		{"merge nothing", `{}`, metadataMerge, `{"region":"eu","seats":3,"tier":"silver"}`},
+		{"replace", `{"tier": "gold"}`, metadataReplace, `{"tier":"gold"}`},
+		{"replace with nothing", `{}`, metadataReplace, `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compactJSON(resolveMetadata(current, mustMetadata(t, tt.edited), tt.mode))
+			if got != tt.want {
+				t.Errorf("resolveMetadata = %s, want %s", got, tt.want)
+			}
+		})
+	}
+	if len(current) != 3 {
+		t.Errorf("resolveMetadata changed the current metadata: %v", current)
+	}
+}
+
+func TestDiffMetadata(t *testing.T) {
+	// before is what the Zep API returns, so its numbers are float64
+	var before map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"tier": "silver", "region": "eu", "seats": 3, "tags": ["a"]}`), &before); err != nil {
+		t.Fatal(err)
+	}
+	after := mustMetadata(t, `{"tier": "gold", "seats": 3, "tags": ["a", "b"], "plan": "annual"}`)
+
+	want := []metadataChange{
+		{Key: "plan", Kind: "added", After: `"annual"`},
+		{Key: "region", Kind: "removed", Before: `"eu"`},
+		{Key: "tags", Kind: "changed", Before: `["a"]`, After: `["a","b"]`},
+		{Key: "tier", Kind: "changed", Before: `"silver"`, After: `"gold"`},
+	}
+	if got := diffMetadata(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffMetadata =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := diffMetadata(before, before); len(got) != 0 {
+		t.Errorf("diffMetadata of equal maps = %+v, want none", got)
+	}
+}
+
+func TestRemovedKeysKept(t *testing.T) {
+	before := map[string]interface{}{"tier": "silver", "region": "eu", "seats": 3.0}
+	intended := map[string]interface{}{"tier": "gold"}
+
+	tests := []struct {
+		name  string
+		saved map[string]interface{}
+		want  []string
+	}{
+		{"server replaced", map[string]interface{}{"tier": "gold"}, nil},
+		{"server merged", map[string]interface{}{"tier": "gold", "region": "eu", "seats": 3.0}, []string{"region", "seats"}},
+		{"server kept one", map[string]interface{}{"tier": "gold", "seats": 3.0}, []string{"seats"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := removedKeysKept(before, intended, tt.saved); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("removedKeysKept = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
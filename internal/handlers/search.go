@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+const (
+	// searchLimit caps the matches a search returns
+	searchLimit = 50
+	// searchScanSessions is how many of the newest sessions a global search
+	// scans when Zep has no search API
+	searchScanSessions = 200
+	// searchScanPageSize is the session page size used to find them
+	searchScanPageSize = 100
+	// snippetRadius is how much text is kept either side of a match
+	snippetRadius = 80
+)
+
+// SearchResult is a message match ready to display
+type SearchResult struct {
+	zepapi.MessageMatch
+	UserID  string // the session's user, when known
+	Snippet string
+	Page    int // the session page holding the message; 0 when unknown
+	Link    string
+}
+
+// Search finds messages across sessions, optionally only one user's. It uses
+// Zep's search API when the server has one, and otherwise scans the message
+// pages of the newest sessions for the phrase.
+func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	userID := strings.TrimSpace(r.URL.Query().Get("user_id"))
+
+	data := map[string]interface{}{
+		"Title":    "Search",
+		"SubTitle": "Find messages across sessions",
+		"Page":     "search",
+		"Path":     r.URL.Path,
+		"BreadCrumbs": []BreadCrumb{
+			{
+				Title: "Search",
+				Path:  r.URL.Path,
+			},
+		},
+		"Query":     query,
+		"UserID":    userID,
+		"MenuItems": GetMenuItems(h.basePath),
+	}
+
+	if query != "" {
+		results, mode, err := h.searchMessages(query, userID)
+		if err != nil {
+			log.Printf("❌ Search for %q failed: %v", query, err)
+			data["Error"] = err.Error()
+		}
+		data["Results"] = results
+		data["Mode"] = mode
+		data["Limit"] = searchLimit
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		if err := h.render(w, r, "SearchContent", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		if err := h.render(w, r, "Layout", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// searchMessages returns the matches and how they were found: "search" when
+// Zep ranked them, "scan" when the sessions were read page by page
+func (h *Handlers) searchMessages(query, userID string) ([]SearchResult, string, error) {
+	matches, err := h.apiClient.SearchMessages(zepapi.MessageSearch{Text: query, UserID: userID, Limit: searchLimit})
+	if err == nil {
+		results := h.searchResults(matches, query)
+		for i := range results {
+			results[i].UserID = userID
+		}
+		return results, "search", nil
+	}
+	if !errors.Is(err, zepapi.ErrSearchUnsupported) {
+		return nil, "search", err
+	}
+
+	sessions, err := h.scanSessions(userID)
+	if err != nil {
+		return nil, "scan", err
+	}
+	if len(sessions) > searchScanSessions {
+		sessions = sessions[:searchScanSessions]
+	}
+	sessionIDs := make([]string, len(sessions))
+	users := make(map[string]string, len(sessions))
+	for i, session := range sessions {
+		sessionIDs[i] = session.SessionID
+		users[session.SessionID] = session.UserID
+	}
+
+	matches, err = h.apiClient.ScanMessages(sessionIDs, query, searchLimit)
+	if err != nil {
+		return nil, "scan", err
+	}
+	results := h.searchResults(matches, query)
+	for i := range results {
+		results[i].UserID = users[results[i].SessionID]
+	}
+	return results, "scan", nil
+}
+
+// scanSessions returns the sessions a scan reads, newest first so recent
+// conversations are found before old ones. A global scan only fetches the
+// first pages of sessions ordered by creation time, not every session.
+func (h *Handlers) scanSessions(userID string) ([]zepapi.Session, error) {
+	if userID != "" {
+		sessions, err := h.apiClient.FindSessions(zepapi.SessionFilter{UserID: userID})
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(sessions, func(i, j int) bool {
+			return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+		})
+		return sessions, nil
+	}
+
+	var sessions []zepapi.Session
+	opts := zepapi.ListOptions{PageSize: searchScanPageSize, OrderBy: "created_at", Asc: false}
+	for opts.PageNumber = 1; opts.PageNumber <= searchScanSessions/searchScanPageSize; opts.PageNumber++ {
+		page, err := h.apiClient.GetSessionsPage(opts)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, page.Sessions...)
+		if len(page.Sessions) < searchScanPageSize {
+			break
+		}
+	}
+	return sessions, nil
+}
+
+// searchResults adds snippets and links that open each session on the page
+// holding the match, with the phrase highlighted
+func (h *Handlers) searchResults(matches []zepapi.MessageMatch, query string) []SearchResult {
+	results := make([]SearchResult, len(matches))
+	for i, match := range matches {
+		result := SearchResult{MessageMatch: match, Snippet: searchSnippet(match.Message.Content, query)}
+		params := url.Values{"q": {query}}
+		if match.Index >= 0 {
+			result.Page = match.Index/defaultPageSize + 1
+			params.Set("page", strconv.Itoa(result.Page))
+		}
+		result.Link = h.basePath + "/sessions/" + url.PathEscape(match.SessionID) + "?" + params.Encode()
+		results[i] = result
+	}
+	return results
+}
+
+// searchSnippet trims content to the text around the first match. Content
+// without an exact match (a similarity hit) is trimmed from the start.
+func searchSnippet(content, query string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	start, end := 0, snippetRadius
+	if loc := matchPattern(query).FindStringIndex(content); loc != nil {
+		start, end = loc[0], loc[1]
+	}
+
+	from := start - snippetRadius
+	if from < 0 {
+		from = 0
+	}
+	to := end + snippetRadius
+	if to > len(content) {
+		to = len(content)
+	}
+	// Keep the cut on rune boundaries
+	for from > 0 && !utf8.RuneStart(content[from]) {
+		from--
+	}
+	for to < len(content) && !utf8.RuneStart(content[to]) {
+		to++
+	}
+
+	snippet := content[from:to]
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if to < len(content) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// matchPattern matches a search phrase anywhere, ignoring case
+func matchPattern(query string) *regexp.Regexp {
+	return regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+}
+
+// sessionPagerPath is the session page URL up to the page number, keeping
+// the search so paging through matches keeps them highlighted
+func sessionPagerPath(path, query string) string {
+	if query == "" {
+		return path + "?page="
+	}
+	return path + "?" + url.Values{"q": {query}}.Encode() + "&page="
+}
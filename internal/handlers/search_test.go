@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+func TestSearchSnippet(t *testing.T) {
+	long := strings.Repeat("a", 100) + " Needle " + strings.Repeat("b", 100)
+
+	tests := []struct {
+		name    string
+		content string
+		query   string
+		want    string
+	}{
+		{"short content", "find the needle here", "needle", "find the needle here"},
+		{"whitespace collapsed", "find\n\tthe   needle", "NEEDLE", "find the needle"},
+		{"cut both sides", long, "needle", "…" + strings.Repeat("a", 79) + " Needle " + strings.Repeat("b", 79) + "…"},
+		{"no exact match keeps the start", strings.Repeat("c", 200), "needle", strings.Repeat("c", 160) + "…"},
+		{"cut on rune boundaries", strings.Repeat("é", 60) + "needle", "needle", "…" + strings.Repeat("é", 40) + "needle"},
+		{"query with regexp characters", "costs $5.00 (net)", "$5.00 (net)", "costs $5.00 (net)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchSnippet(tt.content, tt.query); got != tt.want {
+				t.Errorf("searchSnippet = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchResultsLinkToMessagePage(t *testing.T) {
+	h := &Handlers{basePath: "/admin"}
+	matches := []zepapi.MessageMatch{
+		{SessionID: "s1", Index: 0},
+		{SessionID: "s1", Index: defaultPageSize},
+		{SessionID: "team/a b", Index: -1},
+	}
+
+	want := []struct {
+		page int
+		link string
+	}{
+		{1, "/admin/sessions/s1?page=1&q=refund+policy"},
+		{2, "/admin/sessions/s1?page=2&q=refund+policy"},
+		{0, "/admin/sessions/team%2Fa%20b?q=refund+policy"},
+	}
+	results := h.searchResults(matches, "refund policy")
+	for i, result := range results {
+		if result.Page != want[i].page || result.Link != want[i].link {
+			t.Errorf("result %d = page %d, %q; want page %d, %q", i, result.Page, result.Link, want[i].page, want[i].link)
+		}
+	}
+}
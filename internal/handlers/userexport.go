@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+// userExportManifest describes what a user data archive holds, including
+// the parts that could not be exported
+type userExportManifest struct {
+	UserID     string           `json:"user_id"`
+	ExportedAt time.Time        `json:"exported_at"`
+	ExportedBy string           `json:"exported_by,omitempty"`
+	Files      []userExportFile `json:"files"`
+}
+
+// userExportFile is the outcome of one file in the archive. SessionID is
+// set on session files, whose names may not match the ID.
+type userExportFile struct {
+	JobResult
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// uniqueArchiveName returns base.extension, or base-2.extension and so on
+// when an earlier file took the name. Names are compared case-insensitively
+// because session IDs that differ only in case or in characters
+// exportFilename replaces would overwrite each other when unpacked.
+func uniqueArchiveName(taken map[string]bool, base, extension string) string {
+	name := exportFilename(base, extension)
+	for i := 2; taken[strings.ToLower(name)]; i++ {
+		name = exportFilename(fmt.Sprintf("%s-%d", base, i), extension)
+	}
+	taken[strings.ToLower(name)] = true
+	return name
+}
+
+// StartUserExport builds a zip of everything Zep stores for a user as a
+// background job: the user record, their sessions with every message, their
+// episodes and their graph
+func (h *Handlers) StartUserExport(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userId")
+
+	job := jobTracker.StartJob("user_export", "Export data for "+userID, BreadCrumb{
+		Title: userID,
+		Path:  h.basePath + "/users/" + userID,
+	}, 0)
+	log.Printf("📦 Starting data export job %s for user %s", job.ID, userID)
+
+	entry := h.auditEntry(r, "user.export", "user", userID)
+	entry.Details = map[string]string{"job_id": job.ID}
+
+	go func() {
+		path, err := h.buildUserExport(job.ID, userID, entry.Actor)
+		if err == nil {
+			name := exportFilename("zep-user-"+userID, "zip")
+			jobTracker.SetArtifact(job.ID, path, name, h.basePath+"/jobs/"+job.ID+"/download")
+		}
+		h.recordAudit(entry, err)
+		jobTracker.Finish(job.ID, err)
+	}()
+
+	jobURL := h.basePath + "/jobs/" + job.ID
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  "started",
+			"job_id":  job.ID,
+			"job_url": jobURL,
+		})
+		return
+	}
+	http.Redirect(w, r, jobURL, http.StatusSeeOther)
+}
+
+// buildUserExport writes the archive to a temporary file and returns its
+// path. Only a missing user or a failure to write the archive fails the
+// export; anything else that cannot be fetched is recorded in the manifest.
+func (h *Handlers) buildUserExport(jobID, userID, exportedBy string) (string, error) {
+	user, err := h.apiClient.GetUser(userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load user: %w", err)
+	}
+
+	file, err := os.CreateTemp("", "zep-user-export-*.zip")
+	if err != nil {
+		return "", err
+	}
+	archive := zip.NewWriter(file)
+
+	manifest := userExportManifest{
+		UserID:     userID,
+		ExportedAt: time.Now().UTC(),
+		ExportedBy: exportedBy,
+	}
+	// record notes a file's outcome; only archive write errors are returned
+	record := func(file userExportFile, fetchErr error) {
+		if file.Status == "" {
+			file.Status = "succeeded"
+		}
+		if fetchErr != nil {
+			file.Status = "failed"
+			file.Error = fetchErr.Error()
+			log.Printf("⚠️ Export job %s could not export %s: %v", jobID, file.ID, fetchErr)
+		}
+		manifest.Files = append(manifest.Files, file)
+		jobTracker.AddResult(jobID, file.JobResult)
+	}
+	named := func(name string) userExportFile {
+		return userExportFile{JobResult: JobResult{ID: name}}
+	}
+
+	err = func() error {
+		sessions, sessionsErr := h.apiClient.GetUserSessions(userID)
+		// user.json, sessions.json, episodes.json, graph.json and one file per session
+		jobTracker.SetTotal(jobID, 4+len(sessions))
+
+		if err := writeArchiveJSON(archive, "user.json", user, manifest.ExportedAt); err != nil {
+			return err
+		}
+		record(named("user.json"), nil)
+
+		if sessionsErr == nil {
+			if err := writeArchiveJSON(archive, "sessions.json", sessions, manifest.ExportedAt); err != nil {
+				return err
+			}
+		}
+		record(named("sessions.json"), sessionsErr)
+
+		taken := make(map[string]bool)
+		for i := range sessions {
+			file := named("sessions/" + uniqueArchiveName(taken, sessions[i].SessionID, "json"))
+			file.SessionID = sessions[i].SessionID
+			entry, err := createArchiveEntry(archive, file.ID, manifest.ExportedAt)
+			if err != nil {
+				return err
+			}
+			record(file, h.writeConversation(newJSONConversationWriter(entry), &sessions[i]))
+		}
+
+		episodes, episodesErr := h.apiClient.GetUserEpisodes(userID)
+		if episodesErr == nil {
+			if err := writeArchiveJSON(archive, "episodes.json", episodes, manifest.ExportedAt); err != nil {
+				return err
+			}
+		}
+		record(named("episodes.json"), episodesErr)
+
+		graphFile := named("graph.json")
+		graph, graphErr := h.apiClient.GetUserGraph(userID)
+		if graphErr == nil {
+			if err := writeArchiveJSON(archive, graphFile.ID, graph, manifest.ExportedAt); err != nil {
+				return err
+			}
+			if graph.Partial() {
+				graphFile.Status = "partial"
+				graphFile.Error = fmt.Sprintf("the mentions of %d episode(s) could not be loaded: %s", len(graph.FailedEpisodes), strings.Join(graph.FailedEpisodes, ", "))
+			}
+		}
+		record(graphFile, graphErr)
+
+		return writeArchiveJSON(archive, "manifest.json", manifest, manifest.ExportedAt)
+	}()
+	if err == nil {
+		err = archive.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write archive: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// writeConversation writes a session and all of its messages. A failure
+// part way leaves the output unterminated.
+func (h *Handlers) writeConversation(out conversationWriter, session *zepapi.Session) error {
+	if err := out.Begin(session); err != nil {
+		return err
+	}
+	err := h.walkMessages(context.Background(), session.SessionID, func(messages []zepapi.Message) error {
+		for _, message := range messages {
+			if err := out.Write(message); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return out.End()
+}
+
+// createArchiveEntry adds a compressed file stamped with the export time
+func createArchiveEntry(archive *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	return archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+}
+
+func writeArchiveJSON(archive *zip.Writer, name string, v interface{}, modified time.Time) error {
+	entry, err := createArchiveEntry(archive, name, modified)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import "testing"
+
+func TestUniqueArchiveName(t *testing.T) {
+	taken := make(map[string]bool)
+	tests := []struct {
+		sessionID string
+		want      string
+	}{
+		{"s1", "s1.json"},
+		{"a:b", "a_b.json"},
+		{"a_b", "a_b-2.json"},
+		{"A_B", "A_B-3.json"},
+		{"a_b-2", "a_b-2-2.json"},
+		{"../etc/passwd", ".._etc_passwd.json"},
+	}
+	// Order matters: each name is taken by the rows before it
+	for _, tt := range tests {
+		if got := uniqueArchiveName(taken, tt.sessionID, "json"); got != tt.want {
+			t.Errorf("uniqueArchiveName(%q) = %q, want %q", tt.sessionID, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+// Length limits for the editable user fields
+const (
+	maxNameLength  = 100
+	maxEmailLength = 254
+)
+
+// userField is a profile field the user forms can set or clear
+type userField struct {
+	Name  string
+	Label string
+}
+
+var userFields = []userField{
+	{Name: "first_name", Label: "First name"},
+	{Name: "last_name", Label: "Last name"},
+	{Name: "email", Label: "Email"},
+}
+
+// formErrorField is the error slot for failures that belong to no single
+// field, such as an error from the Zep API
+const formErrorField = "form"
+
+// metadataErrorField is the error slot under the metadata editor
+const metadataErrorField = "metadata"
+
+// fieldError is an inline error shown under a form field. An empty Message
+// clears a previous error.
+type fieldError struct {
+	Field   string
+	Message string
+}
+
+// userFieldChanges returns the fields a form sent, keyed by name. A field
+// left out of the form is unchanged, while one sent empty is cleared.
+func userFieldChanges(form url.Values) (map[string]string, map[string]string) {
+	changes := make(map[string]string)
+	errs := make(map[string]string)
+	for _, field := range userFields {
+		values, ok := form[field.Name]
+		if !ok {
+			continue
+		}
+		value := ""
+		if len(values) > 0 {
+			value = values[0]
+		}
+		if msg := validateUserField(field, value); msg != "" {
+			errs[field.Name] = msg
+			continue
+		}
+		changes[field.Name] = value
+	}
+	return changes, errs
+}
+
+func validateUserField(field userField, value string) string {
+	switch field.Name {
+	case "email":
+		if value == "" {
+			return ""
+		}
+		if len(value) > maxEmailLength {
+			return fmt.Sprintf("Email must be at most %d characters", maxEmailLength)
+		}
+		// Display names like "Ann <ann@example.com>" parse too, so insist
+		// the whole value is the bare address
+		if addr, err := mail.ParseAddress(value); err != nil || addr.Address != value {
+			return "Enter an email address like name@domain.com"
+		}
+	default:
+		if utf8.RuneCountInString(value) > maxNameLength {
+			return fmt.Sprintf("%s must be at most %d characters", field.Label, maxNameLength)
+		}
+	}
+	return ""
+}
+
+// keptUserFields returns a warning for each field the form cleared that the
+// updated user still holds, since Zep may ignore an empty value on update
+func keptUserFields(changes map[string]string, updated *zepapi.User) map[string]string {
+	values := map[string]string{
+		"first_name": updated.FirstName,
+		"last_name":  updated.LastName,
+		"email":      updated.Email,
+	}
+	kept := make(map[string]string)
+	for name, value := range changes {
+		if value == "" && values[name] != "" {
+			kept[name] = fmt.Sprintf("Saved, but the Zep server kept %q. It may not support clearing this field.", values[name])
+		}
+	}
+	return kept
+}
+
+// joinWarnings flattens field warnings into one message for the audit log,
+// in field order
+func joinWarnings(warnings map[string]string) string {
+	var parts []string
+	for _, field := range userFields {
+		if msg := warnings[field.Name]; msg != "" {
+			parts = append(parts, field.Label+": "+msg)
+		}
+	}
+	if msg := warnings[metadataErrorField]; msg != "" {
+		parts = append(parts, "Metadata: "+msg)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// renderFieldErrors answers a form submission with 422 and an out-of-band
+// error for every user field, the metadata editor and the form itself, so
+// stale errors from an earlier attempt clear
+func (h *Handlers) renderFieldErrors(w http.ResponseWriter, errs map[string]string) {
+	h.renderFieldMessages(w, http.StatusUnprocessableEntity, errs)
+}
+
+// renderFieldWarnings answers a saved form with the same out-of-band slots,
+// for changes the Zep server did not apply in full
+func (h *Handlers) renderFieldWarnings(w http.ResponseWriter, warnings map[string]string) {
+	h.renderFieldMessages(w, http.StatusOK, warnings)
+}
+
+func (h *Handlers) renderFieldMessages(w http.ResponseWriter, status int, msgs map[string]string) {
+	fieldErrors := make([]fieldError, 0, len(userFields)+2)
+	for _, field := range userFields {
+		fieldErrors = append(fieldErrors, fieldError{Field: field.Name, Message: msgs[field.Name]})
+	}
+	for _, slot := range []string{metadataErrorField, formErrorField} {
+		fieldErrors = append(fieldErrors, fieldError{Field: slot, Message: msgs[slot]})
+	}
+
+	w.Header().Set("HX-Reswap", "none")
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "FieldErrors", fieldErrors); err != nil {
+		log.Printf("❌ Failed to render field errors: %v", err)
+	}
+}
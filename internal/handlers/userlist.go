@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
+)
+
+// userListKey caches every user along with their session count
+const userListKey = "users:all"
+
+// userListTTL bounds how stale the cached session counts can get, since
+// sessions are also created outside this interface
+const userListTTL = time.Minute
+
+// userListMu makes concurrent requests, such as a search typed a key at a
+// time, wait for one load instead of each starting their own
+var userListMu sync.Mutex
+
+// allUsers returns every user with their session count, loading the list
+// once per userListTTL. Callers must not modify the returned users.
+func (h *Handlers) allUsers() ([]zepapi.User, error) {
+	userListMu.Lock()
+	defer userListMu.Unlock()
+
+	if cached, ok := h.cache.Get(userListKey); ok {
+		if users, ok := cached.([]zepapi.User); ok {
+			return users, nil
+		}
+	}
+	users, err := h.apiClient.GetUsersWithSessionCounts()
+	if err != nil {
+		return nil, err
+	}
+	h.cache.Set(userListKey, users, userListTTL)
+	return users, nil
+}
+
+// invalidateUserList drops the cached user list after users or their
+// sessions change
+func (h *Handlers) invalidateUserList() {
+	h.cache.Delete(userListKey)
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 
+	"github.com/schizoidcock/zep-web-interface/internal/audit"
+	"github.com/schizoidcock/zep-web-interface/internal/auth"
 	"github.com/schizoidcock/zep-web-interface/internal/config"
 	"github.com/schizoidcock/zep-web-interface/internal/handlers"
 	"github.com/schizoidcock/zep-web-interface/internal/zepapi"
@@ -19,18 +21,26 @@ func New(cfg *config.Config) (*http.Server, error) {
 	// Create Zep API client with proxy support
 	apiClient := zepapi.NewClient(cfg.ZepAPIURL, cfg.ZepAPIKey, cfg.ProxyURL)
 
+	// Create handlers with base path
+	basePath := cfg.ProxyPath
+	if basePath == "" {
+		basePath = "/admin"
+	}
+	authenticator := auth.New(cfg, basePath)
+
 	// Load templates with proxy path support
-	templates, err := loadTemplatesWithConfig(cfg.ProxyPath)
+	templates, err := loadTemplatesWithConfig(cfg.ProxyPath, authenticator.LoginEnabled())
 	if err != nil {
 		return nil, fmt.Errorf("failed to load templates: %w", err)
 	}
 
-	// Create handlers with base path
-	basePath := cfg.ProxyPath
-	if basePath == "" {
-		basePath = "/admin"
+	auditLog, err := audit.Open(cfg.AuditLogPath)
+	if err != nil {
+		return nil, err
 	}
-	h := handlers.New(apiClient, templates, basePath, cfg)
+	fmt.Printf("📝 Recording admin actions to %s\n", auditLog.Path())
+
+	h := handlers.New(apiClient, templates, basePath, cfg, authenticator, auditLog)
 
 	// Setup router
 	r := chi.NewRouter()
@@ -38,25 +48,26 @@ func New(cfg *config.Config) (*http.Server, error) {
 	// Middleware
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(timeoutUnlessStreaming(60 * time.Second))
 	
 	// Trust proxy headers if enabled (for Railway, Heroku, etc.)
 	if cfg.TrustProxy {
 		r.Use(middleware.RealIP)
 	}
 	
-	// CORS with configurable origins
+	// CORS with configurable origins. Cookies are only shared with origins
+	// listed explicitly, never with a wildcard.
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   cfg.CORSOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Forwarded-For", "X-Real-IP"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
+		ExposedHeaders:   []string{"Link", "X-CSRF-Token"},
+		AllowCredentials: !allowsAnyOrigin(cfg.CORSOrigins),
 		MaxAge:           300,
 	}))
 
 	// Routes (includes static files)
-	setupRoutes(r, h, cfg)
+	setupRoutes(r, h, cfg, authenticator)
 
 	return &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
@@ -64,7 +75,31 @@ func New(cfg *config.Config) (*http.Server, error) {
 	}, nil
 }
 
-func setupRoutes(r chi.Router, h *handlers.Handlers, cfg *config.Config) {
+// streamingSuffixes are the endpoints that stream for as long as the client
+// keeps reading, so the request timeout must not cut them off. Exports
+// stream downloads of any length, such as a session's whole history, and
+// stop when the client leaves.
+var streamingSuffixes = []string{"/graph/stream", "/export"}
+
+// timeoutUnlessStreaming applies the request timeout to every endpoint
+// except the streaming ones
+func timeoutUnlessStreaming(timeout time.Duration) func(http.Handler) http.Handler {
+	limit := middleware.Timeout(timeout)
+	return func(next http.Handler) http.Handler {
+		limited := limit(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, suffix := range streamingSuffixes {
+				if strings.HasSuffix(r.URL.Path, suffix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
+
+func setupRoutes(r chi.Router, h *handlers.Handlers, cfg *config.Config, authenticator *auth.Authenticator) {
 	// Debug: Log routing configuration
 	fmt.Printf("🔧 PROXY_PATH configuration: '%s'\n", cfg.ProxyPath)
 	if cfg.ProxyPath != "" {
@@ -80,8 +115,8 @@ func setupRoutes(r chi.Router, h *handlers.Handlers, cfg *config.Config) {
 		w.Write([]byte(`{"status":"healthy","service":"zep-web-interface"}`))
 	})
 	
-	// Auth test endpoint
-	r.Get("/auth-test", h.TestAuth)
+	// Auth test endpoint (calls the Zep API, so it needs a signed-in operator)
+	r.With(authenticator.Middleware).Get("/auth-test", h.TestAuth)
 
 	// Static files - serve at both root and proxy path locations
 	staticHandler := http.StripPrefix("/static/", http.FileServer(http.Dir("web/static")))
@@ -93,46 +128,101 @@ func setupRoutes(r chi.Router, h *handlers.Handlers, cfg *config.Config) {
 		r.Handle(proxyStaticPath, http.StripPrefix(strings.TrimSuffix(cfg.ProxyPath, "/")+"/static/", http.FileServer(http.Dir("web/static"))))
 	}
 
-	// Setup routes based on proxy path configuration
-	if cfg.ProxyPath != "" {
-		// Normalize proxy path
-		basePath := cfg.ProxyPath
-		if !strings.HasPrefix(basePath, "/") {
-			basePath = "/" + basePath
-		}
-		if strings.HasSuffix(basePath, "/") && basePath != "/" {
-			basePath = strings.TrimSuffix(basePath, "/")
-		}
+	// Admin pages and their JSON/fragment API, shared by both routing modes.
+	// Everything except the login form requires authentication, and every
+	// POST, PATCH and DELETE a CSRF token.
+	adminRoutes := func(r chi.Router) {
+		r.Use(authenticator.CSRF)
+		r.Get("/login", h.LoginPage)
+		r.Post("/login", h.Login)
+		r.Post("/logout", h.Logout)
+		r.Get("/auth/oidc", h.OIDCLogin)
+		r.Get("/auth/callback", h.OIDCCallback)
 
-		// Direct admin routes at proxy path (PROXY_PATH=/admin means admin routes are AT /admin, not /admin/admin)
-		r.Route(basePath, func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(authenticator.Middleware)
+
+			// Viewers can browse
 			r.Get("/", h.Dashboard)
 			r.Get("/sessions", h.SessionList)
 			r.Get("/sessions/{sessionId}", h.SessionDetails)
-			r.Delete("/sessions/{sessionId}", h.DeleteSession)
+			r.Get("/sessions/{sessionId}/export", h.ExportSession)
 			r.Get("/users", h.UserList)
-			r.Get("/users/create", h.CreateUserForm)
-			r.Post("/users/create", h.CreateUser)
 			r.Get("/users/{userId}", h.UserDetails)
-			r.Patch("/users/{userId}", h.UpdateUser)
-			r.Delete("/users/{userId}", h.DeleteUser)
 			r.Get("/users/{userId}/sessions", h.UserSessions)
 			r.Get("/users/{userId}/episodes", h.UserEpisodes)
+			r.Get("/users/{userId}/episodes/{episodeId}", h.UserEpisode)
 			r.Get("/users/{userId}/graph", h.UserGraph)
+			r.Get("/users/{userId}/graph/search", h.UserGraphSearch)
+			r.Get("/users/{userId}/graph/export", h.ExportUserGraph)
+			r.Get("/users/{userId}/graph/nodes/{nodeId}", h.UserGraphNode)
+			r.Get("/search", h.Search)
+			r.Get("/jobs/{jobId}", h.JobDetails)
+			r.Get("/audit", h.AuditLog)
 			r.Get("/logs", h.Logs)
 			r.Get("/logs/{service}", h.LogsService)
 			r.Get("/settings", h.Settings)
 			r.Get("/service-urls", h.ServiceURLs)
+
+			// Operators can create and edit users
+			r.Group(func(r chi.Router) {
+				r.Use(authenticator.RequireRole(auth.RoleOperator))
+				r.Get("/users/create", h.CreateUserForm)
+				r.Post("/users/create", h.CreateUser)
+				r.Get("/users/import", h.ImportUsersPage)
+				r.Post("/users/import/preview", h.ImportUsersPreview)
+				r.Post("/users/import", h.ImportUsers)
+				r.Patch("/users/{userId}", h.UpdateUser)
+				r.Post("/users/{userId}/metadata/preview", h.UserMetadataPreview)
+				r.Post("/users/{userId}/export", h.StartUserExport)
+				r.Get("/jobs/{jobId}/download", h.JobDownload)
+			})
+
+			// Only admins can delete
+			r.Group(func(r chi.Router) {
+				r.Use(authenticator.RequireRole(auth.RoleAdmin))
+				r.Post("/sessions/bulk-delete", h.BulkDeleteSessions)
+				r.Delete("/sessions/{sessionId}", h.DeleteSession)
+				r.Post("/users/bulk-delete", h.BulkDeleteUsers)
+				r.Delete("/users/{userId}", h.DeleteUserEnhanced)
+				r.Post("/users/{userId}/sessions/retry-delete", h.RetrySessionDeletion)
+			})
 		})
+	}
+
+	apiRoutes := func(r chi.Router) {
+		r.Use(authenticator.CSRF)
+		r.Use(authenticator.Middleware)
+		r.Get("/me", h.CurrentIdentity)
+		r.Get("/sessions", h.SessionListAPI)
+		r.With(authenticator.RequireRole(auth.RoleAdmin)).Get("/sessions/bulk-delete/preview", h.BulkDeleteSessionsPreview)
+		r.Get("/users", h.UserListAPI)
+		r.Get("/jobs/{jobId}", h.JobStatusAPI)
+		r.Get("/audit", h.AuditExport)
+		r.Get("/users/{userId}/deletion-status", h.DeletionStatus)
+		r.Get("/users/{userId}/episodes", h.UserEpisodesAPI)
+		r.Get("/users/{userId}/episodes/async", h.UserEpisodesAsync)
+		r.Get("/users/{userId}/graph", h.UserGraphAPI)
+		r.Get("/users/{userId}/graph/async", h.UserGraphAsync)
+		r.Get("/users/{userId}/graph/stream", h.UserGraphStream)
+	}
+
+	// Setup routes based on proxy path configuration
+	if cfg.ProxyPath != "" {
+		// Normalize proxy path
+		basePath := cfg.ProxyPath
+		if !strings.HasPrefix(basePath, "/") {
+			basePath = "/" + basePath
+		}
+		if strings.HasSuffix(basePath, "/") && basePath != "/" {
+			basePath = strings.TrimSuffix(basePath, "/")
+		}
+
+		// Direct admin routes at proxy path (PROXY_PATH=/admin means admin routes are AT /admin, not /admin/admin)
+		r.Route(basePath, adminRoutes)
 
 		// API routes under proxy path
-		apiPath := basePath + "/api"
-		r.Route(apiPath, func(r chi.Router) {
-			r.Get("/sessions", h.SessionListAPI)
-			r.Get("/users", h.UserListAPI)
-			r.Get("/users/{userId}/episodes", h.UserEpisodesAPI)
-			r.Get("/users/{userId}/graph", h.UserGraphAPI)
-		})
+		r.Route(basePath+"/api", apiRoutes)
 	} else {
 		// Default routes (no proxy path)
 		// Redirect root to admin
@@ -141,33 +231,12 @@ func setupRoutes(r chi.Router, h *handlers.Handlers, cfg *config.Config) {
 		})
 
 		// Admin routes at root level
-		r.Route("/admin", func(r chi.Router) {
-			r.Get("/", h.Dashboard)
-			r.Get("/sessions", h.SessionList)
-			r.Get("/sessions/{sessionId}", h.SessionDetails)
-			r.Delete("/sessions/{sessionId}", h.DeleteSession)
-			r.Get("/users", h.UserList)
-			r.Get("/users/create", h.CreateUserForm)
-			r.Post("/users/create", h.CreateUser)
-			r.Get("/users/{userId}", h.UserDetails)
-			r.Patch("/users/{userId}", h.UpdateUser)
-			r.Delete("/users/{userId}", h.DeleteUser)
-			r.Get("/users/{userId}/sessions", h.UserSessions)
-			r.Get("/users/{userId}/episodes", h.UserEpisodes)
-			r.Get("/users/{userId}/graph", h.UserGraph)
-			r.Get("/logs", h.Logs)
-			r.Get("/logs/{service}", h.LogsService)
-			r.Get("/settings", h.Settings)
-			r.Get("/service-urls", h.ServiceURLs)
-		})
+		r.Route("/admin", adminRoutes)
 
-		// API routes at root level
-		r.Route("/api", func(r chi.Router) {
-			r.Get("/sessions", h.SessionListAPI)
-			r.Get("/users", h.UserListAPI)
-			r.Get("/users/{userId}/episodes", h.UserEpisodesAPI)
-			r.Get("/users/{userId}/graph", h.UserGraphAPI)
-		})
+		// API routes under /admin/api, which is where the handlers and
+		// templates point, and at /api for existing callers
+		r.Route("/admin/api", apiRoutes)
+		r.Route("/api", apiRoutes)
 	}
 	
 	// Debug: Add a catch-all route to help debug 404s
@@ -193,3 +262,12 @@ func setupRoutes(r chi.Router, h *handlers.Handlers, cfg *config.Config) {
 	})
 }
 
+// allowsAnyOrigin reports whether CORS_ORIGINS contains the "*" wildcard
+func allowsAnyOrigin(origins []string) bool {
+	for _, origin := range origins {
+		if origin == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -5,16 +5,18 @@ import (
 	"fmt"
 	"html/template"
 	"reflect"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/Masterminds/sprig/v3"
 )
 
 func loadTemplates() (*template.Template, error) {
-	return loadTemplatesWithConfig("")
+	return loadTemplatesWithConfig("", false)
 }
 
-func loadTemplatesWithConfig(proxyPath string) (*template.Template, error) {
+func loadTemplatesWithConfig(proxyPath string, loginEnabled bool) (*template.Template, error) {
 	// Create base template function map from sprig (like v0.27)
 	funcMap := sprig.FuncMap()
 	
@@ -36,6 +38,10 @@ func loadTemplatesWithConfig(proxyPath string) (*template.Template, error) {
 		}
 		return basePath + path
 	}
+	// Whether operators sign in through the login form (and can sign out)
+	funcMap["loginEnabled"] = func() bool {
+		return loginEnabled
+	}
 	funcMap["safeLen"] = func(slice interface{}) int {
 		if slice == nil {
 			return 0
@@ -73,6 +79,24 @@ func loadTemplatesWithConfig(proxyPath string) (*template.Template, error) {
 		}
 		return template.JS(string(jsonBytes))
 	}
+	// Escapes text and marks every case-insensitive occurrence of query
+	funcMap["highlight"] = func(text, query string) template.HTML {
+		if query == "" {
+			return template.HTML(template.HTMLEscapeString(text))
+		}
+		pattern := regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+		var b strings.Builder
+		last := 0
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			b.WriteString(template.HTMLEscapeString(text[last:loc[0]]))
+			b.WriteString(`<mark class="bg-yellow-100 rounded-sm">`)
+			b.WriteString(template.HTMLEscapeString(text[loc[0]:loc[1]]))
+			b.WriteString(`</mark>`)
+			last = loc[1]
+		}
+		b.WriteString(template.HTMLEscapeString(text[last:]))
+		return template.HTML(b.String())
+	}
 	funcMap["Percent"] = func(part, total float64) float64 {
 		// Calculate percentage - placeholder
 		if total == 0 {
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -153,6 +156,7 @@ type Episode struct {
 	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
 	Role        string     `json:"role,omitempty"`
 	Processed   bool       `json:"processed,omitempty"`
+	SessionID   string     `json:"session_id,omitempty"` // session the episode came from, for message episodes
 }
 
 // Graph data structures - Episodes are used as edges in Zep's graph implementation
@@ -164,6 +168,7 @@ type GraphNode struct {
 	Attributes map[string]interface{} `json:"attributes,omitempty"`
 	CreatedAt  string                 `json:"created_at"`
 	UpdatedAt  string                 `json:"updated_at"`
+	Episodes   []string               `json:"episodes,omitempty"` // episodes that mention the node
 }
 
 // GraphEpisode represents the relationship/edge between nodes in Zep's graph
@@ -181,23 +186,112 @@ type GraphEpisode struct {
 	ValidAt          string    `json:"valid_at,omitempty"`
 	ExpiredAt        string    `json:"expired_at,omitempty"`
 	InvalidAt        string    `json:"invalid_at,omitempty"`
+	// Episodes that mention the edge: Zep's own list for it, then any
+	// other episode it was found in. Content and Summary describe the first.
+	Episodes         []string  `json:"episodes,omitempty"`
 }
 
-// RawTriplet represents a graph triplet with episode as the connecting relationship
-type RawTriplet struct {
-	SourceNode GraphNode    `json:"sourceNode"`
-	Episode    GraphEpisode `json:"episode"`
-	TargetNode GraphNode    `json:"targetNode"`
+// States of an edge's fact at a point in time, see GraphEpisode.StateAt
+const (
+	EdgeValid   = "valid"
+	EdgeFuture  = "future"  // not yet recorded, or not yet true
+	EdgeInvalid = "invalid" // invalidated or expired by then
+)
+
+// StateAt reports whether the edge's fact was known and true at t. A
+// missing or unreadable timestamp leaves that side of the interval open.
+func (e GraphEpisode) StateAt(t time.Time) string {
+	if created, ok := ParseGraphTime(e.CreatedAt); ok && created.After(t) {
+		return EdgeFuture
+	}
+	if valid, ok := ParseGraphTime(e.ValidAt); ok && valid.After(t) {
+		return EdgeFuture
+	}
+	if invalid, ok := ParseGraphTime(e.InvalidAt); ok && !invalid.After(t) {
+		return EdgeInvalid
+	}
+	if expired, ok := ParseGraphTime(e.ExpiredAt); ok && !expired.After(t) {
+		return EdgeInvalid
+	}
+	return EdgeValid
+}
+
+// graphTimeLayouts are the timestamp forms Zep's graph API has been seen
+// to return
+var graphTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+}
+
+// ParseGraphTime parses a graph timestamp, reporting false when it is empty
+// or in an unknown form
+func ParseGraphTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range graphTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Graph is a user's knowledge graph with every node and edge listed once
+type Graph struct {
+	Nodes []GraphNode    `json:"nodes"`
+	Edges []GraphEpisode `json:"edges"`
+
+	// FailedEpisodes are the episodes whose mentions could not be loaded,
+	// so their nodes and edges may be missing
+	FailedEpisodes []string `json:"failed_episodes,omitempty"`
 }
 
+// Partial reports whether some episodes failed to load
+func (g *Graph) Partial() bool {
+	return len(g.FailedEpisodes) > 0
+}
+
+// UnknownTotal is the total of a list page when the server did not report how
+// many rows there are across all pages
+const UnknownTotal = -1
+
 type SessionsResponse struct {
 	Sessions []Session `json:"sessions"`
-	Total    int       `json:"total"`
+	Total    int       `json:"total"` // UnknownTotal when not reported
 }
 
 type UsersResponse struct {
 	Users []User `json:"users"`
-	Total int    `json:"total"`
+	Total int    `json:"total"` // UnknownTotal when not reported
+}
+
+// ListOptions controls paging and ordering for the ordered list endpoints
+type ListOptions struct {
+	PageNumber int    // 1-based page number
+	PageSize   int    // rows per page
+	OrderBy    string // column to order by, e.g. "created_at"
+	Asc        bool   // ascending when true, descending otherwise
+}
+
+// normalize fills in defaults for unset paging fields
+func (o ListOptions) normalize() ListOptions {
+	if o.PageNumber < 1 {
+		o.PageNumber = 1
+	}
+	if o.PageSize < 1 {
+		o.PageSize = 10
+	}
+	if o.OrderBy == "" {
+		o.OrderBy = "created_at"
+	}
+	return o
+}
+
+// offset returns the index of the first row on the requested page
+func (o ListOptions) offset() int {
+	return (o.PageNumber - 1) * o.PageSize
 }
 
 // API methods for Zep v1.0.2 (uses v2 API endpoints)
@@ -220,18 +314,65 @@ func (c *Client) GetSessions() ([]Session, error) {
 		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
 	}
 
+	sessions, _ := parseSessionsBody(body)
+	return sessions, nil
+}
+
+// GetSessionsPage fetches a single ordered page of sessions. The total in the
+// returned response is the number of sessions across all pages, or
+// UnknownTotal when the server doesn't report it.
+func (c *Client) GetSessionsPage(opts ListOptions) (*SessionsResponse, error) {
+	opts = opts.normalize()
+
+	query := url.Values{}
+	query.Set("page_number", strconv.Itoa(opts.PageNumber))
+	query.Set("page_size", strconv.Itoa(opts.PageSize))
+	query.Set("order_by", opts.OrderBy)
+	query.Set("asc", strconv.FormatBool(opts.Asc))
+
+	resp, err := c.get("/api/v2/sessions-ordered?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	sessions, total := parseSessionsBody(body)
+
+	// Older servers ignore the paging parameters and return every session,
+	// so order and slice locally when we get more rows than we asked for
+	if len(sessions) > opts.PageSize {
+		total = len(sessions)
+		sortSessions(sessions, opts.OrderBy, opts.Asc)
+		sessions = pageSlice(sessions, opts)
+	}
+
+	log.Printf("✅ Parsed %d sessions (page %d, total %d)", len(sessions), opts.PageNumber, total)
+	return &SessionsResponse{Sessions: sessions, Total: total}, nil
+}
+
+// parseSessionsBody decodes the supported session list formats and returns the
+// sessions along with the reported total count (or UnknownTotal if absent)
+func parseSessionsBody(body []byte) ([]Session, int) {
 	// Try to parse as paginated response first
 	var paginatedResp struct {
-		Sessions []Session `json:"sessions"`
-		Total    int       `json:"total_count"`
-		TotalCount int     `json:"total_count"`
-		RowCount   int     `json:"row_count"`
+		Sessions   []Session `json:"sessions"`
+		TotalCount *int      `json:"total_count"`
+		RowCount   int       `json:"row_count"`
 	}
-	
+
 	if err := json.Unmarshal(body, &paginatedResp); err == nil {
 		if len(paginatedResp.Sessions) > 0 {
 			log.Printf("✅ Parsed %d sessions from paginated response", len(paginatedResp.Sessions))
-			return paginatedResp.Sessions, nil
+			return paginatedResp.Sessions, reportedTotal(len(paginatedResp.Sessions), paginatedResp.TotalCount)
 		}
 	}
 
@@ -268,8 +409,13 @@ func (c *Client) GetSessions() ([]Session, error) {
 				}
 			}
 			
+			total := UnknownTotal
+			if totalCount, ok := responseObj["total_count"].(float64); ok {
+				total = max(int(totalCount), len(parsedSessions))
+			}
+
 			log.Printf("✅ Parsed %d sessions from object response", len(parsedSessions))
-			return parsedSessions, nil
+			return parsedSessions, total
 		}
 	}
 
@@ -277,12 +423,47 @@ func (c *Client) GetSessions() ([]Session, error) {
 	var sessions []Session
 	if err := json.Unmarshal(body, &sessions); err == nil {
 		log.Printf("✅ Parsed %d sessions from direct array", len(sessions))
-		return sessions, nil
+		return sessions, UnknownTotal
 	}
 	
 	// If all parsing attempts fail, return empty slice instead of error
 	log.Printf("⚠️ No sessions found or unknown format, returning empty slice")
-	return []Session{}, nil
+	return []Session{}, 0
+}
+
+// sortSessions orders sessions in place by one of the session table keys
+func sortSessions(sessions []Session, orderBy string, asc bool) {
+	less := func(a, b Session) bool {
+		switch orderBy {
+		case "session_id":
+			return a.SessionID < b.SessionID
+		case "user_id":
+			return a.UserID < b.UserID
+		case "updated_at":
+			return a.UpdatedAt.Before(b.UpdatedAt)
+		default:
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if asc {
+			return less(sessions[i], sessions[j])
+		}
+		return less(sessions[j], sessions[i])
+	})
+}
+
+// pageSlice returns the rows of items that fall on the requested page
+func pageSlice[T any](items []T, opts ListOptions) []T {
+	start := opts.offset()
+	if start >= len(items) {
+		return []T{}
+	}
+	end := start + opts.PageSize
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
 }
 
 func (c *Client) GetSession(sessionID string) (*Session, error) {
@@ -318,10 +499,15 @@ func (c *Client) DeleteSession(sessionID string) error {
 }
 
 func (c *Client) GetMessageList(sessionID string, page, pageSize int) ([]Message, int, error) {
+	return c.GetMessageListContext(context.Background(), sessionID, page, pageSize)
+}
+
+// GetMessageListContext is GetMessageList, abandoned when ctx is done
+func (c *Client) GetMessageListContext(ctx context.Context, sessionID string, page, pageSize int) ([]Message, int, error) {
 	// Build URL with pagination parameters
 	endpoint := fmt.Sprintf("/api/v2/sessions/%s/messages?page=%d&page_size=%d", sessionID, page, pageSize)
 	
-	resp, err := c.get(endpoint)
+	resp, err := c.requestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -412,9 +598,126 @@ func (c *Client) GetMessageList(sessionID string, page, pageSize int) ([]Message
 	return []Message{}, 0, nil
 }
 
+// usersPageSize is the page size used when walking every page of users
+const usersPageSize = 100
+
+// SessionFilter selects sessions for bulk operations. Zero-valued fields
+// match every session.
+type SessionFilter struct {
+	UserID        string
+	CreatedBefore time.Time
+	Ended         *bool // nil matches both ended and active sessions
+}
+
+// IsEmpty reports whether the filter matches every session
+func (f SessionFilter) IsEmpty() bool {
+	return f.UserID == "" && f.CreatedBefore.IsZero() && f.Ended == nil
+}
+
+// Matches reports whether a session satisfies the filter
+func (f SessionFilter) Matches(s Session) bool {
+	if f.UserID != "" && s.UserID != f.UserID {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !s.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	if f.Ended != nil && (s.EndedAt != nil) != *f.Ended {
+		return false
+	}
+	return true
+}
+
+// FindSessions returns every session matching the filter. A user filter is
+// resolved through the user's sessions; otherwise all session pages are walked.
+func (c *Client) FindSessions(filter SessionFilter) ([]Session, error) {
+	matches, _, err := c.ScanSessions(filter, 0)
+	return matches, err
+}
+
+// ScanSessions returns the sessions matching the filter among roughly the
+// first limit sessions, walking whole pages, and reports whether every
+// session was scanned. A limit of 0 scans them all.
+func (c *Client) ScanSessions(filter SessionFilter, limit int) ([]Session, bool, error) {
+	var candidates []Session
+	complete := true
+	if filter.UserID != "" {
+		sessions, err := c.GetUserSessions(filter.UserID)
+		if err != nil {
+			return nil, false, err
+		}
+		candidates = sessions
+	} else {
+		opts := ListOptions{PageNumber: 1, PageSize: usersPageSize, OrderBy: "created_at"}
+		for {
+			page, err := c.GetSessionsPage(opts)
+			if err != nil {
+				return nil, false, err
+			}
+			candidates = append(candidates, page.Sessions...)
+
+			// Stop on a short page or once we've seen the reported total
+			if len(page.Sessions) < opts.PageSize || reachedTotal(len(candidates), page.Total) {
+				break
+			}
+			if limit > 0 && len(candidates) >= limit {
+				complete = false
+				break
+			}
+			opts.PageNumber++
+		}
+	}
+
+	matches := []Session{}
+	for _, s := range candidates {
+		if filter.Matches(s) {
+			matches = append(matches, s)
+		}
+	}
+	log.Printf("🔍 Found %d of %d sessions matching filter", len(matches), len(candidates))
+	return matches, complete, nil
+}
+
+// GetUsers fetches every user by walking all pages of the ordered users endpoint
 func (c *Client) GetUsers() ([]User, error) {
+	var users []User
+	opts := ListOptions{PageNumber: 1, PageSize: usersPageSize, OrderBy: "created_at"}
+
+	for {
+		page, err := c.GetUsersPage(opts)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, page.Users...)
+
+		// Stop on a short page or once we've seen the reported total
+		if len(page.Users) < opts.PageSize || reachedTotal(len(users), page.Total) {
+			break
+		}
+		opts.PageNumber++
+	}
+
+	if users == nil {
+		users = []User{}
+	}
+	log.Printf("✅ Fetched %d users across %d page(s)", len(users), opts.PageNumber)
+	return users, nil
+}
+
+// GetUsersPage fetches a single ordered page of users. The total in the
+// returned response is the number of users across all pages, or UnknownTotal
+// when the server doesn't report it.
+func (c *Client) GetUsersPage(opts ListOptions) (*UsersResponse, error) {
+	opts = opts.normalize()
+
+	query := url.Values{}
+	query.Set("pageNumber", strconv.Itoa(opts.PageNumber))
+	query.Set("pageSize", strconv.Itoa(opts.PageSize))
+	query.Set("order_by", opts.OrderBy)
+	query.Set("asc", strconv.FormatBool(opts.Asc))
+
 	// Use the proper ordered users endpoint as per official API
-	resp, err := c.get("/api/v2/users-ordered?pageNumber=1&pageSize=100")
+	resp, err := c.get("/api/v2/users-ordered?" + query.Encode())
 	if err != nil {
 		return nil, err
 	}
@@ -426,23 +729,103 @@ func (c *Client) GetUsers() ([]User, error) {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 	
-	log.Printf("🔍 DEBUG GetUsers - Status: %d, Response: %s", resp.StatusCode, string(body))
+	log.Printf("🔍 DEBUG GetUsersPage - Page: %d, Size: %d, Order: %s, Asc: %v, Status: %d",
+		opts.PageNumber, opts.PageSize, opts.OrderBy, opts.Asc, resp.StatusCode)
 
 	if resp.StatusCode >= 400 {
 		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
 	}
 
+	users, total := parseUsersBody(body)
+
+	// Servers that ignore the paging parameters return every user, so order
+	// and slice locally when we get more rows than we asked for
+	if len(users) > opts.PageSize {
+		total = len(users)
+		sortUsers(users, opts.OrderBy, opts.Asc)
+		users = pageSlice(users, opts)
+	}
+
+	return &UsersResponse{Users: users, Total: total}, nil
+}
+
+// SearchUsers returns one page of the users whose user ID, email or name
+// contains the search text (case-insensitive), ordered by any user table key.
+// The ordered users endpoint has no search support and no session_count
+// column, so both are done locally over every user.
+func SearchUsers(users []User, search string, opts ListOptions) *UsersResponse {
+	opts = opts.normalize()
+
+	needle := strings.ToLower(strings.TrimSpace(search))
+	matched := make([]User, 0, len(users))
+	for _, user := range users {
+		if needle == "" || userMatches(user, needle) {
+			matched = append(matched, user)
+		}
+	}
+	sortUsers(matched, opts.OrderBy, opts.Asc)
+
+	log.Printf("🔍 User search %q matched %d of %d users", search, len(matched), len(users))
+	return &UsersResponse{Users: pageSlice(matched, opts), Total: len(matched)}
+}
+
+// userMatches reports whether a lower-cased needle occurs in any searchable user field
+func userMatches(user User, needle string) bool {
+	fields := []string{
+		user.UserID,
+		user.Email,
+		user.FirstName,
+		user.LastName,
+		user.FirstName + " " + user.LastName,
+	}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortUsers orders users in place by one of the user table keys
+func sortUsers(users []User, orderBy string, asc bool) {
+	less := func(a, b User) bool {
+		switch orderBy {
+		case "user_id":
+			return a.UserID < b.UserID
+		case "email":
+			return strings.ToLower(a.Email) < strings.ToLower(b.Email)
+		case "session_count":
+			return a.SessionCount < b.SessionCount
+		default:
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		if asc {
+			return less(users[i], users[j])
+		}
+		return less(users[j], users[i])
+	})
+}
+
+// parseUsersBody decodes the supported user list formats and returns the users
+// along with the reported total count (or UnknownTotal if absent)
+func parseUsersBody(body []byte) ([]User, int) {
 	// Try to parse as ordered response first
 	var orderedResp struct {
-		Users     []User `json:"users"`
-		RowCount  int    `json:"row_count"`
-		TotalCount int   `json:"total_count"`
-		Total     int    `json:"total"`
+		Users      []User `json:"users"`
+		RowCount   int    `json:"row_count"`
+		TotalCount *int   `json:"total_count"`
+		Total      *int   `json:"total"`
 	}
 	if err := json.Unmarshal(body, &orderedResp); err == nil {
 		if len(orderedResp.Users) > 0 {
 			log.Printf("✅ Parsed %d users from ordered response", len(orderedResp.Users))
-			return orderedResp.Users, nil
+			reported := orderedResp.TotalCount
+			if reported == nil {
+				reported = orderedResp.Total
+			}
+			return orderedResp.Users, reportedTotal(len(orderedResp.Users), reported)
 		}
 	}
 
@@ -483,9 +866,14 @@ func (c *Client) GetUsers() ([]User, error) {
 					parsedUsers = append(parsedUsers, usr)
 				}
 			}
+
+			total := UnknownTotal
+			if totalCount, ok := responseObj["total_count"].(float64); ok {
+				total = max(int(totalCount), len(parsedUsers))
+			}
 			
 			log.Printf("✅ Parsed %d users from object response", len(parsedUsers))
-			return parsedUsers, nil
+			return parsedUsers, total
 		}
 	}
 
@@ -493,21 +881,46 @@ func (c *Client) GetUsers() ([]User, error) {
 	var users []User
 	if err := json.Unmarshal(body, &users); err == nil {
 		log.Printf("✅ Parsed %d users from direct array", len(users))
-		return users, nil
+		return users, UnknownTotal
 	}
 	
 	// If all parsing attempts fail, return empty slice instead of error
 	log.Printf("⚠️ No users found or unknown format, returning empty slice")
-	return []User{}, nil
+	return []User{}, 0
+}
+
+// reportedTotal is the total a list response reported, never less than the
+// rows it returned, or UnknownTotal when it reported none
+func reportedTotal(rows int, reported *int) int {
+	if reported == nil {
+		return UnknownTotal
+	}
+	return max(*reported, rows)
 }
 
-// GetUsersWithSessionCounts fetches users with their session counts in a single optimized call
+// reachedTotal reports whether a page walk has seen every row of a known total
+func reachedTotal(seen, total int) bool {
+	return total != UnknownTotal && seen >= total
+}
+
+// GetUsersWithSessionCounts fetches every user along with their session counts
 func (c *Client) GetUsersWithSessionCounts() ([]User, error) {
 	users, err := c.GetUsers()
 	if err != nil {
 		return nil, err
 	}
 
+	c.FillSessionCounts(users)
+	return users, nil
+}
+
+// FillSessionCounts fetches session counts for the given users concurrently and
+// stores them on each user in place
+func (c *Client) FillSessionCounts(users []User) {
+	if len(users) == 0 {
+		return
+	}
+
 	// Create concurrent channel-based session count fetcher
 	type sessionCountResult struct {
 		index int
@@ -547,7 +960,6 @@ func (c *Client) GetUsersWithSessionCounts() ([]User, error) {
 	}
 
 	log.Printf("✅ Fetched session counts for %d users concurrently", len(users))
-	return users, nil
 }
 
 func (c *Client) GetUsersLegacy() ([]User, error) {
@@ -651,125 +1063,312 @@ func getMapFromInterface(val interface{}) map[string]interface{} {
 	return nil
 }
 
-// GetUserGraphTriplets fetches graph triplets for a specific user with optimized concurrent processing
-func (c *Client) GetUserGraphTriplets(userID string) ([]RawTriplet, error) {
-	// Step 1: Get user episodes first
-	episodes, err := c.GetUserEpisodes(userID)
+// GetUserGraph fetches the graph of a specific user with optimized concurrent processing
+func (c *Client) GetUserGraph(userID string) (*Graph, error) {
+	return c.GetUserGraphWithProgress(userID, nil)
+}
+
+// GetUserGraphWithProgress is GetUserGraph with a callback that is invoked
+// once the episodes are known and after every mentions call, with the
+// number of episodes processed so far and the total episode count
+func (c *Client) GetUserGraphWithProgress(userID string, progressCallback func(completed, total int)) (*Graph, error) {
+	builder := NewGraphBuilder()
+	err := c.StreamUserGraph(context.Background(), userID, func(chunk GraphChunk) error {
+		if progressCallback != nil {
+			progressCallback(chunk.Completed, chunk.Total)
+		}
+		builder.Add(chunk)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user episodes: %w", err)
+		return nil, err
+	}
+
+	graph := builder.Graph()
+	log.Printf("✅ Built graph for user %s: %d nodes, %d edges, %d failed episodes (concurrent)", userID, len(graph.Nodes), len(graph.Edges), len(graph.FailedEpisodes))
+	return graph, nil
+}
+
+// GraphChunk is one step of a streamed graph load: the nodes and edges
+// mentioned by a single episode. The first chunk carries no episode and only
+// announces how many episodes there are.
+type GraphChunk struct {
+	EpisodeID string         `json:"episode_id,omitempty"`
+	Nodes     []GraphNode    `json:"nodes,omitempty"`
+	Edges     []GraphEpisode `json:"edges,omitempty"`
+	Error     string         `json:"error,omitempty"` // the episode's mentions could not be loaded
+	Completed int            `json:"completed"`
+	Total     int            `json:"total"`
+}
+
+// StreamUserGraph loads a user's graph episode by episode, calling fn with
+// each episode's mentions as soon as they arrive. fn is never called
+// concurrently. Cancelling ctx, or fn returning an error, stops the
+// outstanding mentions calls and returns that error.
+func (c *Client) StreamUserGraph(ctx context.Context, userID string, fn func(GraphChunk) error) error {
+	episodes, err := c.GetUserEpisodesContext(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user episodes: %w", err)
 	}
 
 	log.Printf("🔍 Found %d episodes for user %s", len(episodes), userID)
 
-	if len(episodes) == 0 {
-		return []RawTriplet{}, nil
+	total := len(episodes)
+	if err := fn(GraphChunk{Total: total}); err != nil {
+		return err
 	}
 
-	var triplets []RawTriplet
-	var mu sync.Mutex
-	nodeMap := make(map[string]GraphNode) // Track unique nodes
-	
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// Use worker pool for concurrent episode processing
 	const maxWorkers = 3 // Limit concurrent requests
 	semaphore := make(chan struct{}, maxWorkers)
+	chunks := make(chan GraphChunk)
 	var wg sync.WaitGroup
-	
-	// Process episodes concurrently
+
 	for _, episode := range episodes {
 		wg.Add(1)
 		go func(ep Episode) {
 			defer wg.Done()
-			semaphore <- struct{}{} // Acquire
+			select {
+			case semaphore <- struct{}{}: // Acquire
+			case <-ctx.Done():
+				return
+			}
 			defer func() { <-semaphore }() // Release
 
-			mentions, err := c.GetEpisodeMentions(ep.EpisodeID)
+			chunk := GraphChunk{EpisodeID: ep.EpisodeID}
+			mentions, err := c.GetEpisodeMentionsContext(ctx, ep.EpisodeID)
 			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
 				log.Printf("⚠️ Failed to get mentions for episode %s: %v", ep.EpisodeID, err)
-				return
+				chunk.Error = err.Error()
+			} else {
+				for _, node := range mentions.Nodes {
+					chunk.Nodes = append(chunk.Nodes, newGraphNode(node, ep))
+				}
+				for _, edge := range mentions.Edges {
+					chunk.Edges = append(chunk.Edges, newGraphEdge(edge, ep))
+				}
 			}
 
-			mu.Lock()
-			defer mu.Unlock()
-
-			// Store all nodes from this episode
-			for _, node := range mentions.Nodes {
-				nodeMap[node.UUID] = GraphNode{
-					UUID:       node.UUID,
-					Name:       node.Name,
-					Summary:    node.Summary,
-					Labels:     node.Labels,
-					Attributes: node.Attributes,
-					CreatedAt:  node.CreatedAt,
-					UpdatedAt:  node.UpdatedAt,
-				}
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+			}
+		}(episode)
+	}
+	go func() {
+		wg.Wait()
+		close(chunks)
+	}()
+
+	completed := 0
+	for chunk := range chunks {
+		completed++
+		chunk.Completed = completed
+		chunk.Total = total
+		if err := fn(chunk); err != nil {
+			cancel()
+			// Let the workers see the cancellation and exit
+			for range chunks {
 			}
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-			// Build triplets from edges
-			for _, edge := range mentions.Edges {
-				sourceNode, sourceExists := nodeMap[edge.SourceNodeUUID]
-				targetNode, targetExists := nodeMap[edge.TargetNodeUUID]
-				
-				if !sourceExists || !targetExists {
-					// Check if nodes are in current batch
-					for _, node := range mentions.Nodes {
-						if node.UUID == edge.SourceNodeUUID {
-							sourceNode = GraphNode{
-								UUID: node.UUID, Name: node.Name, Summary: node.Summary,
-								Labels: node.Labels, Attributes: node.Attributes,
-								CreatedAt: node.CreatedAt, UpdatedAt: node.UpdatedAt,
-							}
-							sourceExists = true
-						}
-						if node.UUID == edge.TargetNodeUUID {
-							targetNode = GraphNode{
-								UUID: node.UUID, Name: node.Name, Summary: node.Summary,
-								Labels: node.Labels, Attributes: node.Attributes,
-								CreatedAt: node.CreatedAt, UpdatedAt: node.UpdatedAt,
-							}
-							targetExists = true
-						}
-					}
-				}
-				
-				if !sourceExists || !targetExists {
-					log.Printf("⚠️ Missing nodes for edge %s (source: %v, target: %v)", edge.UUID, sourceExists, targetExists)
-					continue
-				}
+	log.Printf("✅ Streamed graph for user %s from %d episodes", userID, total)
+	return nil
+}
 
-				triplet := RawTriplet{
-					SourceNode: sourceNode,
-					Episode: GraphEpisode{
-						UUID:           edge.UUID,
-						SourceNodeUUID: edge.SourceNodeUUID,
-						TargetNodeUUID: edge.TargetNodeUUID,
-						Type:           "relationship",
-						Name:           edge.Name,
-						Fact:           edge.Fact,
-						Content:        ep.Content,
-						Summary:        ep.Description,
-						CreatedAt:      edge.CreatedAt,
-						UpdatedAt:      edge.UpdatedAt,
-						ValidAt:        getStringValue(edge.ValidAt),
-						ExpiredAt:      getStringValue(edge.ExpiredAt),
-						InvalidAt:      getStringValue(edge.InvalidAt),
-					},
-					TargetNode: targetNode,
-				}
-				
-				triplets = append(triplets, triplet)
+// newGraphNode describes a node along with the episode it was mentioned in
+func newGraphNode(node *EntityNode, ep Episode) GraphNode {
+	return GraphNode{
+		UUID:       node.UUID,
+		Name:       node.Name,
+		Summary:    node.Summary,
+		Labels:     node.Labels,
+		Attributes: node.Attributes,
+		CreatedAt:  node.CreatedAt,
+		UpdatedAt:  node.UpdatedAt,
+		Episodes:   []string{ep.EpisodeID},
+	}
+}
+
+// newGraphEdge describes an edge along with the episode it was mentioned in
+func newGraphEdge(edge *EntityEdge, ep Episode) GraphEpisode {
+	episodes := edge.Episodes
+	if !containsString(episodes, ep.EpisodeID) {
+		episodes = append(append([]string{}, episodes...), ep.EpisodeID)
+	}
+	return GraphEpisode{
+		UUID:           edge.UUID,
+		SourceNodeUUID: edge.SourceNodeUUID,
+		TargetNodeUUID: edge.TargetNodeUUID,
+		Type:           "relationship",
+		Name:           edge.Name,
+		Fact:           edge.Fact,
+		Content:        ep.Content,
+		Summary:        ep.Description,
+		CreatedAt:      edge.CreatedAt,
+		UpdatedAt:      edge.UpdatedAt,
+		ValidAt:        getStringValue(edge.ValidAt),
+		ExpiredAt:      getStringValue(edge.ExpiredAt),
+		InvalidAt:      getStringValue(edge.InvalidAt),
+		Episodes:       episodes,
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// GraphBuilder assembles a Graph from streamed graph chunks. Chunks may
+// arrive in any order: nodes and edges are merged by UUID, and edges are
+// only matched to their nodes once every chunk is in.
+type GraphBuilder struct {
+	nodes map[string]GraphNode
+	edges map[string]GraphEpisode
+	// contentFrom is the episode each edge's Content and Summary came from
+	contentFrom map[string]string
+	failed      []string
+}
+
+func NewGraphBuilder() *GraphBuilder {
+	return &GraphBuilder{
+		nodes:       make(map[string]GraphNode),
+		edges:       make(map[string]GraphEpisode),
+		contentFrom: make(map[string]string),
+	}
+}
+
+// Add merges a chunk's nodes and edges into the graph. Nodes and edges
+// collect the episodes of every mention. A node mentioned again replaces
+// the earlier copy if it was updated since; an edge keeps the content of
+// the first episode in its list. A chunk with an error marks the graph
+// partial.
+func (b *GraphBuilder) Add(chunk GraphChunk) {
+	if chunk.Error != "" && chunk.EpisodeID != "" {
+		b.failed = append(b.failed, chunk.EpisodeID)
+	}
+	for _, node := range chunk.Nodes {
+		existing, ok := b.nodes[node.UUID]
+		if !ok {
+			b.nodes[node.UUID] = node
+			continue
+		}
+		episodes := existing.Episodes
+		for _, episode := range node.Episodes {
+			if !containsString(episodes, episode) {
+				episodes = append(episodes, episode)
 			}
-		}(episode)
+		}
+		if graphTimeAfter(node.UpdatedAt, existing.UpdatedAt) {
+			existing = node
+		}
+		existing.Episodes = episodes
+		b.nodes[node.UUID] = existing
+	}
+	for _, edge := range chunk.Edges {
+		existing, ok := b.edges[edge.UUID]
+		if !ok {
+			b.edges[edge.UUID] = edge
+			b.contentFrom[edge.UUID] = chunk.EpisodeID
+			continue
+		}
+		for _, episode := range edge.Episodes {
+			if !containsString(existing.Episodes, episode) {
+				existing.Episodes = append(existing.Episodes, episode)
+			}
+		}
+		if episodeRank(existing.Episodes, chunk.EpisodeID) < episodeRank(existing.Episodes, b.contentFrom[edge.UUID]) {
+			existing.Content = edge.Content
+			existing.Summary = edge.Summary
+			b.contentFrom[edge.UUID] = chunk.EpisodeID
+		}
+		b.edges[edge.UUID] = existing
 	}
+}
 
-	wg.Wait()
+// Graph returns the nodes sorted by name and the edges by creation time.
+// Edges whose nodes were never mentioned are left out.
+func (b *GraphBuilder) Graph() *Graph {
+	graph := &Graph{
+		Nodes: make([]GraphNode, 0, len(b.nodes)),
+		Edges: make([]GraphEpisode, 0, len(b.edges)),
+	}
+	for _, node := range b.nodes {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	for _, edge := range b.edges {
+		_, sourceExists := b.nodes[edge.SourceNodeUUID]
+		_, targetExists := b.nodes[edge.TargetNodeUUID]
+		if !sourceExists || !targetExists {
+			log.Printf("⚠️ Missing nodes for edge %s (source: %v, target: %v)", edge.UUID, sourceExists, targetExists)
+			continue
+		}
+		graph.Edges = append(graph.Edges, edge)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		if graph.Nodes[i].Name != graph.Nodes[j].Name {
+			return graph.Nodes[i].Name < graph.Nodes[j].Name
+		}
+		return graph.Nodes[i].UUID < graph.Nodes[j].UUID
+	})
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].CreatedAt != graph.Edges[j].CreatedAt {
+			return graphTimeAfter(graph.Edges[j].CreatedAt, graph.Edges[i].CreatedAt)
+		}
+		return graph.Edges[i].UUID < graph.Edges[j].UUID
+	})
+	if len(b.failed) > 0 {
+		graph.FailedEpisodes = append([]string(nil), b.failed...)
+		sort.Strings(graph.FailedEpisodes)
+	}
+	return graph
+}
 
-	log.Printf("✅ Built %d graph triplets for user %s from %d episodes (concurrent)", len(triplets), userID, len(episodes))
-	return triplets, nil
+// episodeRank is an episode's position in an edge's episode list, with
+// episodes missing from it ranked last
+func episodeRank(episodes []string, episode string) int {
+	for i, e := range episodes {
+		if e == episode {
+			return i
+		}
+	}
+	return len(episodes)
+}
+
+// graphTimeAfter reports whether timestamp a is later than b, falling back
+// to comparing the strings when either cannot be read
+func graphTimeAfter(a, b string) bool {
+	ta, okA := ParseGraphTime(a)
+	tb, okB := ParseGraphTime(b)
+	if okA && okB {
+		return ta.After(tb)
+	}
+	return a > b
 }
 
 // GetEpisodeMentions fetches nodes and edges mentioned in a specific episode
 func (c *Client) GetEpisodeMentions(episodeUUID string) (*EpisodeMentions, error) {
-	resp, err := c.get("/api/v2/graph/episodes/" + episodeUUID + "/mentions")
+	return c.GetEpisodeMentionsContext(context.Background(), episodeUUID)
+}
+
+// GetEpisodeMentionsContext is GetEpisodeMentions, abandoned when ctx is done
+func (c *Client) GetEpisodeMentionsContext(ctx context.Context, episodeUUID string) (*EpisodeMentions, error) {
+	resp, err := c.requestWithContext(ctx, "GET", "/api/v2/graph/episodes/"+episodeUUID+"/mentions", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -829,9 +1428,60 @@ type EntityEdge struct {
 	InvalidAt      *string                `json:"invalid_at,omitempty"`
 }
 
+// Graph search scopes
+const (
+	GraphScopeEdges = "edges"
+	GraphScopeNodes = "nodes"
+)
+
+// GraphSearch is a query against a user's graph
+type GraphSearch struct {
+	Query  string
+	UserID string
+	Scope  string // GraphScopeEdges or GraphScopeNodes
+	Limit  int
+}
+
+// GraphSearchResults holds the edges or nodes a graph search found, best
+// match first, with their scores set
+type GraphSearchResults struct {
+	Edges []*EntityEdge `json:"edges,omitempty"`
+	Nodes []*EntityNode `json:"nodes,omitempty"`
+}
+
+// SearchGraph runs Zep's graph search over a user's graph
+func (c *Client) SearchGraph(search GraphSearch) (*GraphSearchResults, error) {
+	query := map[string]interface{}{
+		"query":   search.Query,
+		"user_id": search.UserID,
+		"scope":   search.Scope,
+	}
+	if search.Limit > 0 {
+		query["limit"] = search.Limit
+	}
+
+	resp, err := c.post("/api/v2/graph/search", query)
+	if err != nil {
+		return nil, err
+	}
+
+	var results GraphSearchResults
+	if err := decodeResponse(resp, &results); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🔍 Graph search %q for user %s found %d edges and %d nodes", search.Query, search.UserID, len(results.Edges), len(results.Nodes))
+	return &results, nil
+}
+
 // GetUserEpisodes fetches episodes for a specific user from the graph API
 func (c *Client) GetUserEpisodes(userID string) ([]Episode, error) {
-	resp, err := c.get("/api/v2/graph/episodes/user/" + userID)
+	return c.GetUserEpisodesContext(context.Background(), userID)
+}
+
+// GetUserEpisodesContext is GetUserEpisodes, abandoned when ctx is done
+func (c *Client) GetUserEpisodesContext(ctx context.Context, userID string) ([]Episode, error) {
+	resp, err := c.requestWithContext(ctx, "GET", "/api/v2/graph/episodes/user/"+userID, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -904,22 +1554,88 @@ func (c *Client) DeleteUser(userID string) error {
 	return nil
 }
 
+// SessionDeletionError records a session that could not be deleted
+type SessionDeletionError struct {
+	SessionID string `json:"session_id"`
+	Error     string `json:"error"`
+}
+
+// UserDeletionResult describes what a user cleanup actually removed
+type UserDeletionResult struct {
+	UserID          string                 `json:"user_id"`
+	SessionsTotal   int                    `json:"sessions_total"`
+	SessionsDeleted int                    `json:"sessions_deleted"`
+	FailedSessions  []SessionDeletionError `json:"failed_sessions"`
+	SessionsError   string                 `json:"sessions_error,omitempty"` // set when the user's sessions could not be listed
+	UserDeleted     bool                   `json:"user_deleted"`
+}
+
+// Partial reports whether the user was deleted but some of their sessions
+// may have been left behind
+func (r *UserDeletionResult) Partial() bool {
+	return r.UserDeleted && (len(r.FailedSessions) > 0 || r.SessionsError != "")
+}
+
+// FailedSessionIDs returns the IDs of sessions that could not be deleted
+func (r *UserDeletionResult) FailedSessionIDs() []string {
+	ids := make([]string, len(r.FailedSessions))
+	for i, f := range r.FailedSessions {
+		ids[i] = f.SessionID
+	}
+	return ids
+}
+
+// PartialDeletionError is returned through BulkDeleteUsers' callback when a
+// user was deleted but some of their sessions were not
+type PartialDeletionError struct {
+	Result *UserDeletionResult
+}
+
+func (e *PartialDeletionError) Error() string {
+	if e.Result.SessionsError != "" {
+		return fmt.Sprintf("user deleted but their sessions could not be listed: %s", e.Result.SessionsError)
+	}
+	return fmt.Sprintf("user deleted but %d of %d sessions could not be deleted", len(e.Result.FailedSessions), e.Result.SessionsTotal)
+}
+
 // DeleteUserWithCleanup deletes a user and performs comprehensive cleanup with optimized concurrency
-func (c *Client) DeleteUserWithCleanup(userID string) error {
+func (c *Client) DeleteUserWithCleanup(userID string) (*UserDeletionResult, error) {
+	return c.DeleteUserWithProgress(userID, nil)
+}
+
+// DeleteUserWithProgress deletes a user like DeleteUserWithCleanup, reporting
+// session deletion progress. The callback is called with (0, total) once the
+// user's sessions are known and again after each session is deleted; when
+// completed == total only the user itself remains to be deleted.
+//
+// Session failures don't stop the user from being deleted; they are collected
+// in the result so callers can report a partial deletion and retry them.
+func (c *Client) DeleteUserWithProgress(userID string, progressCallback func(completed, total int)) (*UserDeletionResult, error) {
 	log.Printf("🧹 Starting optimized user deletion for: %s", userID)
+	result := &UserDeletionResult{
+		UserID:         userID,
+		FailedSessions: []SessionDeletionError{},
+	}
 	
 	// Step 1: Get all sessions for this user first
 	sessions, err := c.GetUserSessions(userID)
 	if err != nil {
 		log.Printf("⚠️ Could not get sessions for user %s (continuing): %v", userID, err)
+		result.SessionsError = err.Error()
 		sessions = []Session{} // Continue with empty sessions
 	} else {
 		log.Printf("📋 Found %d sessions for user %s", len(sessions), userID)
 	}
+	result.SessionsTotal = len(sessions)
+	
+	if progressCallback != nil {
+		progressCallback(0, len(sessions))
+	}
 	
 	// Step 2: Delete sessions concurrently (major optimization)
 	if len(sessions) > 0 {
-		c.deleteSessionsConcurrently(sessions)
+		result.FailedSessions = c.deleteSessionsConcurrently(sessions, progressCallback)
+		result.SessionsDeleted = len(sessions) - len(result.FailedSessions)
 	}
 	
 	// Step 3: Delete the user from Zep server (includes graph cleanup)
@@ -928,11 +1644,16 @@ func (c *Client) DeleteUserWithCleanup(userID string) error {
 	err = c.DeleteUser(userID)
 	if err != nil {
 		log.Printf("❌ Failed to delete user %s from Zep server: %v", userID, err)
-		return fmt.Errorf("failed to delete user from Zep server: %w", err)
+		return result, fmt.Errorf("failed to delete user from Zep server: %w", err)
 	}
+	result.UserDeleted = true
 	
-	log.Printf("✅ User deletion completed for: %s (including graph data cleanup)", userID)
-	return nil
+	if result.Partial() {
+		log.Printf("⚠️ User deletion completed for %s with %d failed session(s)", userID, len(result.FailedSessions))
+	} else {
+		log.Printf("✅ User deletion completed for: %s (including graph data cleanup)", userID)
+	}
+	return result, nil
 }
 
 // BulkDeleteUsers deletes multiple users concurrently with progress tracking
@@ -957,7 +1678,10 @@ func (c *Client) BulkDeleteUsers(userIDs []string, progressCallback func(complet
 			semaphore <- struct{}{} // Acquire
 			defer func() { <-semaphore }() // Release
 			
-			err := c.DeleteUserWithCleanup(uid)
+			result, err := c.DeleteUserWithCleanup(uid)
+			if err == nil && result.Partial() {
+				err = &PartialDeletionError{Result: result}
+			}
 			
 			mu.Lock()
 			completed++
@@ -981,38 +1705,76 @@ func (c *Client) BulkDeleteUsers(userIDs []string, progressCallback func(complet
 	return nil
 }
 
-// deleteSessionsConcurrently deletes multiple sessions in parallel
-func (c *Client) deleteSessionsConcurrently(sessions []Session) {
-	log.Printf("🚀 Starting concurrent session deletion for %d sessions", len(sessions))
+// BulkDeleteSessions deletes multiple sessions concurrently, reporting the
+// outcome of each deletion through progressCallback
+func (c *Client) BulkDeleteSessions(sessionIDs []string, progressCallback func(completed, total int, sessionID string, err error)) error {
+	if len(sessionIDs) == 0 {
+		return fmt.Errorf("no sessions provided for bulk deletion")
+	}
+	
+	log.Printf("🚀 Starting concurrent session deletion for %d sessions", len(sessionIDs))
 	
 	// Limit concurrent deletions to avoid overwhelming the server
 	maxWorkers := 3
-	if len(sessions) < maxWorkers {
-		maxWorkers = len(sessions)
+	if len(sessionIDs) < maxWorkers {
+		maxWorkers = len(sessionIDs)
 	}
 	
 	semaphore := make(chan struct{}, maxWorkers)
 	var wg sync.WaitGroup
+	var completed int
+	var mu sync.Mutex
 	
-	for _, session := range sessions {
+	for _, sessionID := range sessionIDs {
 		wg.Add(1)
-		go func(s Session) {
+		go func(sid string) {
 			defer wg.Done()
 			semaphore <- struct{}{} // Acquire
 			defer func() { <-semaphore }() // Release
 			
-			log.Printf("🗑️ Deleting session: %s", s.SessionID)
-			err := c.DeleteSession(s.SessionID)
+			log.Printf("🗑️ Deleting session: %s", sid)
+			err := c.DeleteSession(sid)
 			if err != nil {
-				log.Printf("⚠️ Failed to delete session %s: %v", s.SessionID, err)
+				log.Printf("⚠️ Failed to delete session %s: %v", sid, err)
 			} else {
-				log.Printf("✅ Successfully deleted session: %s", s.SessionID)
+				log.Printf("✅ Successfully deleted session: %s", sid)
 			}
-		}(session)
+			
+			// Report under the lock so counts never go backwards
+			mu.Lock()
+			completed++
+			if progressCallback != nil {
+				progressCallback(completed, len(sessionIDs), sid, err)
+			}
+			mu.Unlock()
+		}(sessionID)
 	}
 	
 	wg.Wait()
 	log.Printf("✅ Concurrent session deletion completed")
+	return nil
+}
+
+// deleteSessionsConcurrently deletes multiple sessions in parallel, calling
+// progressCallback (if set) after each deletion attempt. It returns the
+// sessions that could not be deleted.
+func (c *Client) deleteSessionsConcurrently(sessions []Session, progressCallback func(completed, total int)) []SessionDeletionError {
+	sessionIDs := make([]string, len(sessions))
+	for i, s := range sessions {
+		sessionIDs[i] = s.SessionID
+	}
+	
+	// The callback runs under BulkDeleteSessions' lock, so appending is safe
+	failed := []SessionDeletionError{}
+	c.BulkDeleteSessions(sessionIDs, func(completed, total int, sessionID string, err error) {
+		if err != nil {
+			failed = append(failed, SessionDeletionError{SessionID: sessionID, Error: err.Error()})
+		}
+		if progressCallback != nil {
+			progressCallback(completed, total)
+		}
+	})
+	return failed
 }
 
 // CreateUser creates a new user
@@ -1127,4 +1889,157 @@ func (c *Client) GetServerHealth() (map[string]interface{}, error) {
 	
 	log.Printf("✅ Health check result: %+v", responseData)
 	return responseData, nil
-}
\ No newline at end of file
+}
+
+// ErrSearchUnsupported is returned when the Zep server has no search API for
+// the request, so callers can fall back to scanning
+var ErrSearchUnsupported = errors.New("search is not supported by this Zep server")
+
+// MessageSearch is a search for messages across sessions
+type MessageSearch struct {
+	Text   string
+	UserID string // only search this user's sessions when set
+	Limit  int
+}
+
+// MessageMatch is a message found by a search
+type MessageMatch struct {
+	SessionID string  `json:"session_id"`
+	Message   Message `json:"message"`
+	Score     float64 `json:"score,omitempty"` // relevance from Zep's search; 0 when found by scanning
+	Index     int     `json:"index"`           // position in the session, oldest first; -1 when unknown
+}
+
+// SearchMessages searches messages across sessions with Zep's session search
+// API, which ranks by similarity rather than matching the text exactly. It
+// returns ErrSearchUnsupported when the server does not offer it.
+func (c *Client) SearchMessages(search MessageSearch) ([]MessageMatch, error) {
+	limit := search.Limit
+	if limit < 1 {
+		limit = 20
+	}
+	query := map[string]interface{}{
+		"text":         search.Text,
+		"search_scope": "messages",
+		"search_type":  "similarity",
+	}
+	if search.UserID != "" {
+		query["user_id"] = search.UserID
+	}
+
+	resp, err := c.post("/api/v2/sessions/search?limit="+strconv.Itoa(limit), query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil, ErrSearchUnsupported
+	}
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Results []struct {
+			SessionID string   `json:"session_id"`
+			Message   *Message `json:"message"`
+			Score     float64  `json:"score"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	matches := []MessageMatch{}
+	for _, r := range result.Results {
+		if r.Message == nil {
+			continue
+		}
+		matches = append(matches, MessageMatch{SessionID: r.SessionID, Message: *r.Message, Score: r.Score, Index: -1})
+	}
+	log.Printf("🔍 Message search %q matched %d messages", search.Text, len(matches))
+	return matches, nil
+}
+
+// scanPageSize is the page size used when scanning a session's messages
+const scanPageSize = 100
+
+// ScanMessages finds messages whose content contains text (case-insensitive)
+// by reading every message page of each session, a few sessions at a time.
+// It stops once limit matches are found; a limit below 1 finds them all.
+// Matches are ordered by session, in the order given, then by position.
+func (c *Client) ScanMessages(sessionIDs []string, text string, limit int) ([]MessageMatch, error) {
+	needle := strings.ToLower(strings.TrimSpace(text))
+	if needle == "" {
+		return []MessageMatch{}, nil
+	}
+
+	maxWorkers := 4
+	semaphore := make(chan struct{}, maxWorkers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	found := 0
+	perSession := make([][]MessageMatch, len(sessionIDs))
+	var firstErr error
+
+	done := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return limit > 0 && found >= limit
+	}
+
+	for i, sessionID := range sessionIDs {
+		wg.Add(1)
+		go func(i int, sid string) {
+			defer wg.Done()
+			semaphore <- struct{}{}        // Acquire
+			defer func() { <-semaphore }() // Release
+
+			for page := 1; !done(); page++ {
+				messages, total, err := c.GetMessageList(sid, page, scanPageSize)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("session %s: %w", sid, err)
+					}
+					mu.Unlock()
+					return
+				}
+				for j, message := range messages {
+					if strings.Contains(strings.ToLower(message.Content), needle) {
+						mu.Lock()
+						perSession[i] = append(perSession[i], MessageMatch{
+							SessionID: sid,
+							Message:   message,
+							Index:     (page-1)*scanPageSize + j,
+						})
+						found++
+						mu.Unlock()
+					}
+				}
+				if len(messages) < scanPageSize || (total > 0 && page*scanPageSize >= total) {
+					return
+				}
+			}
+		}(i, sessionID)
+	}
+	wg.Wait()
+
+	matches := []MessageMatch{}
+	for _, sessionMatches := range perSession {
+		matches = append(matches, sessionMatches...)
+	}
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	log.Printf("🔍 Scanned %d sessions for %q: %d matches", len(sessionIDs), text, len(matches))
+
+	// A failed session only matters when nothing else could be scanned
+	if firstErr != nil && len(matches) == 0 {
+		return nil, firstErr
+	}
+	return matches, nil
+}
@@ -0,0 +1,86 @@
+package zepapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGraphBuilderDeduplicates(t *testing.T) {
+	b := NewGraphBuilder()
+	// ep2 arrives first but ranks after ep1 in the edge's own list
+	b.Add(GraphChunk{
+		EpisodeID: "ep2",
+		Nodes: []GraphNode{
+			{UUID: "n1", Name: "Ann", Summary: "old", UpdatedAt: "2024-01-01T00:00:00Z", Episodes: []string{"ep2"}},
+			{UUID: "n2", Name: "Bob", Episodes: []string{"ep2"}},
+		},
+		Edges: []GraphEpisode{
+			{UUID: "e1", SourceNodeUUID: "n1", TargetNodeUUID: "n2", CreatedAt: "2024-01-02T00:00:00Z", Content: "from ep2", Episodes: []string{"ep1", "ep2"}},
+			{UUID: "e0", SourceNodeUUID: "n2", TargetNodeUUID: "n1", CreatedAt: "2024-01-01T00:00:00Z", Episodes: []string{"ep2"}},
+		},
+	})
+	b.Add(GraphChunk{
+		EpisodeID: "ep1",
+		Nodes: []GraphNode{
+			{UUID: "n1", Name: "Ann", Summary: "new", UpdatedAt: "2024-02-01T00:00:00Z", Episodes: []string{"ep1"}},
+		},
+		Edges: []GraphEpisode{
+			{UUID: "e1", SourceNodeUUID: "n1", TargetNodeUUID: "n2", CreatedAt: "2024-01-02T00:00:00Z", Content: "from ep1", Episodes: []string{"ep1", "ep2"}},
+			{UUID: "e9", SourceNodeUUID: "n1", TargetNodeUUID: "n9", Episodes: []string{"ep1"}},
+		},
+	})
+	b.Add(GraphChunk{
+		EpisodeID: "ep3",
+		Nodes: []GraphNode{
+			{UUID: "n1", Name: "Ann", Summary: "stale", UpdatedAt: "2023-12-01T00:00:00Z", Episodes: []string{"ep3"}},
+		},
+		Edges: []GraphEpisode{
+			{UUID: "e1", SourceNodeUUID: "n1", TargetNodeUUID: "n2", Content: "from ep3", Episodes: []string{"ep3"}},
+		},
+	})
+
+	graph := b.Graph()
+	if len(graph.Nodes) != 2 || graph.Nodes[0].UUID != "n1" || graph.Nodes[1].UUID != "n2" {
+		t.Fatalf("nodes = %+v, want n1 then n2", graph.Nodes)
+	}
+	ann := graph.Nodes[0]
+	if ann.Summary != "new" {
+		t.Errorf("n1 summary = %q, want the most recently updated copy", ann.Summary)
+	}
+	if want := []string{"ep2", "ep1", "ep3"}; !reflect.DeepEqual(ann.Episodes, want) {
+		t.Errorf("n1 episodes = %v, want %v", ann.Episodes, want)
+	}
+
+	// e9 points at a node no episode mentioned
+	if len(graph.Edges) != 2 || graph.Edges[0].UUID != "e0" || graph.Edges[1].UUID != "e1" {
+		t.Fatalf("edges = %+v, want e0 then e1", graph.Edges)
+	}
+	e1 := graph.Edges[1]
+	if e1.Content != "from ep1" {
+		t.Errorf("e1 content = %q, want the first episode in its list", e1.Content)
+	}
+	if want := []string{"ep1", "ep2", "ep3"}; !reflect.DeepEqual(e1.Episodes, want) {
+		t.Errorf("e1 episodes = %v, want %v", e1.Episodes, want)
+	}
+	if graph.Partial() {
+		t.Errorf("graph is partial with failed episodes %v", graph.FailedEpisodes)
+	}
+}
+
+func TestGraphBuilderRecordsFailedEpisodes(t *testing.T) {
+	b := NewGraphBuilder()
+	b.Add(GraphChunk{EpisodeID: "ep5", Error: "timeout"})
+	b.Add(GraphChunk{EpisodeID: "ep1", Nodes: []GraphNode{{UUID: "n1", Name: "Ann"}}})
+	b.Add(GraphChunk{EpisodeID: "ep2", Error: "API error 500"})
+
+	graph := b.Graph()
+	if want := []string{"ep2", "ep5"}; !reflect.DeepEqual(graph.FailedEpisodes, want) {
+		t.Errorf("FailedEpisodes = %v, want %v", graph.FailedEpisodes, want)
+	}
+	if !graph.Partial() {
+		t.Error("graph with failed episodes is not partial")
+	}
+	if len(graph.Nodes) != 1 {
+		t.Errorf("nodes = %+v, want the one that loaded", graph.Nodes)
+	}
+}
@@ -0,0 +1,92 @@
+// Poll an async loader endpoint until it reports success or error.
+// The endpoint returns {status, progress, message, completed, total, data, error}.
+window.pollAsyncLoad = function(statusUrl, onProgress, onSuccess, onError) {
+    let stopped = false;
+
+    function poll() {
+        if (stopped) return;
+        fetch(statusUrl, { headers: { 'Accept': 'application/json' } })
+            .then(response => {
+                if (!response.ok) throw new Error('HTTP ' + response.status);
+                return response.json();
+            })
+            .then(result => {
+                if (stopped) return;
+                if (result.status === 'success') {
+                    onSuccess(result);
+                } else if (result.status === 'error') {
+                    onError(result.error || 'Loading failed');
+                } else {
+                    onProgress(result);
+                    setTimeout(poll, 1000);
+                }
+            })
+            .catch(error => {
+                if (!stopped) onError(error.message);
+            });
+    }
+
+    poll();
+    return function() { stopped = true; };
+};
+
+// Update an AsyncProgress block from a poll result
+window.showAsyncProgress = function(id, result) {
+    const el = document.getElementById(id);
+    if (!el) return;
+    el.querySelector('[data-async-bar]').style.width = (result.progress || 0) + '%';
+    let message = result.message || 'Loading...';
+    if (result.total) {
+        message += ' (' + (result.progress || 0) + '%)';
+    }
+    el.querySelector('[data-async-message]').textContent = message;
+};
+
+// Replace an AsyncProgress block with an error message
+window.showAsyncError = function(id, error) {
+    const el = document.getElementById(id);
+    if (!el) return;
+    el.innerHTML = '<div class="text-sm text-destructive"></div>';
+    el.firstChild.textContent = 'Failed to load: ' + error;
+};
+
+// Read an NDJSON stream, calling onItem with each parsed line. Returns a
+// function that aborts the request; it is also aborted when HTMX swaps the
+// page content, so leaving the page stops the server-side work.
+window.streamNDJSON = function(url, onItem, onDone, onError) {
+    const controller = new AbortController();
+    function abortOnNavigate(event) {
+        if (event.detail.target && event.detail.target.id === 'page-content') {
+            controller.abort();
+        }
+    }
+    document.addEventListener('htmx:beforeSwap', abortOnNavigate);
+    function cleanup() {
+        document.removeEventListener('htmx:beforeSwap', abortOnNavigate);
+    }
+
+    fetch(url, { headers: { 'Accept': 'application/x-ndjson' }, signal: controller.signal })
+        .then(async response => {
+            if (!response.ok) throw new Error('HTTP ' + response.status);
+            const reader = response.body.getReader();
+            const decoder = new TextDecoder();
+            let buffered = '';
+            for (;;) {
+                const { value, done } = await reader.read();
+                if (done) break;
+                buffered += decoder.decode(value, { stream: true });
+                const lines = buffered.split('\n');
+                buffered = lines.pop();
+                lines.filter(line => line.trim()).forEach(line => onItem(JSON.parse(line)));
+            }
+            if (buffered.trim()) onItem(JSON.parse(buffered));
+            cleanup();
+            onDone();
+        })
+        .catch(error => {
+            cleanup();
+            if (error.name !== 'AbortError') onError(error.message);
+        });
+
+    return function() { controller.abort(); };
+};
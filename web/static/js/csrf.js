@@ -0,0 +1,15 @@
+// Every state-changing request must carry the CSRF token the server put in
+// the page's <meta name="csrf-token"> tag. HTMX requests get it
+// automatically; fetch calls pass their headers through csrfHeaders().
+window.csrfToken = function() {
+    const meta = document.querySelector('meta[name="csrf-token"]');
+    return meta ? meta.content : '';
+};
+
+window.csrfHeaders = function(headers) {
+    return Object.assign({ 'X-CSRF-Token': window.csrfToken() }, headers || {});
+};
+
+document.addEventListener('htmx:configRequest', function(event) {
+    event.detail.headers['X-CSRF-Token'] = window.csrfToken();
+});
@@ -0,0 +1,17 @@
+// A 422 response carries inline field errors as out-of-band swaps, so let
+// HTMX swap it instead of treating it as a failed request
+document.addEventListener('htmx:beforeSwap', function(event) {
+    if (event.detail.xhr.status === 422) {
+        event.detail.shouldSwap = true;
+        event.detail.isError = false;
+    }
+});
+
+// Error slots hidden while empty, like the one under the metadata editor,
+// show once a response swaps a message into them and hide when it clears
+document.addEventListener('htmx:oobAfterSwap', function(event) {
+    const slot = event.detail.target;
+    if (slot && slot.id && slot.id.endsWith('-error')) {
+        slot.classList.toggle('hidden', !slot.textContent.trim());
+    }
+});
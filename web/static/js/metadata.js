@@ -0,0 +1,34 @@
+// Check the metadata editor holds a JSON object. The buttons marked
+// data-metadata-action stay disabled until it does.
+window.validateMetadata = function(textarea) {
+    if (!textarea) return true;
+    const errorBox = document.getElementById('metadata-error');
+    let message = '';
+    const text = textarea.value.trim();
+    if (text) {
+        try {
+            const value = JSON.parse(text);
+            if (value === null || typeof value !== 'object' || Array.isArray(value)) {
+                message = 'Metadata must be a JSON object, like {"tenant_id": "acme"}';
+            }
+        } catch (error) {
+            message = 'Not valid JSON: ' + error.message;
+        }
+    }
+
+    errorBox.textContent = message;
+    errorBox.classList.toggle('hidden', !message);
+    document.querySelectorAll('[data-metadata-action]').forEach(button => {
+        button.disabled = !!message;
+    });
+    return !message;
+};
+
+// Don't send a form whose metadata editor holds invalid JSON
+document.addEventListener('htmx:beforeRequest', function(event) {
+    const form = event.detail.elt.closest('form');
+    const textarea = form && form.querySelector('textarea[name="metadata"]');
+    if (textarea && event.detail.requestConfig.verb !== 'get' && !window.validateMetadata(textarea)) {
+        event.preventDefault();
+    }
+});